@@ -0,0 +1,172 @@
+package gqlschema
+
+import (
+	"fmt"
+
+	mschema "github.com/kinvey/odata-schema/mediation-schema"
+)
+
+type schemaDiffer struct {
+	changes []mschema.Change
+}
+
+func (d *schemaDiffer) report(level mschema.ChangeLevel, kind mschema.ChangeKind, path string, format string, args ...interface{}) {
+	d.changes = append(d.changes, mschema.Change{Level: level, Kind: kind, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func isDeprecated(element Element) bool {
+	if element.Directives == nil {
+		return false
+	}
+	for _, directive := range *element.Directives {
+		if directive.Name == "deprecated" {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldType(field Field) string {
+	if field.Required {
+		return field.Type + "!"
+	}
+	return field.Type
+}
+
+// Fields of the definition by name, in the order they are declared
+func fieldsByName(fields *[]Field) ([]string, map[string]Field) {
+	names := []string{}
+	byName := make(map[string]Field)
+	if fields != nil {
+		for _, field := range *fields {
+			names = append(names, field.Name)
+			byName[field.Name] = field
+		}
+	}
+	return names, byName
+}
+
+// Compares fields which clients write, i.e. arguments and the fields of inputs
+func (d *schemaDiffer) inputFields(path string, old *[]Field, new *[]Field, what string) {
+	oldNames, oldFields := fieldsByName(old)
+	newNames, newFields := fieldsByName(new)
+
+	for _, name := range oldNames {
+		fieldPath := fmt.Sprintf(path, name)
+		newField, found := newFields[name]
+		switch {
+		case !found:
+			d.report(mschema.ChangeBreaking, mschema.ChangeRemoved, fieldPath, "%s was removed", what)
+		case oldFields[name].Type != newField.Type:
+			d.report(mschema.ChangeBreaking, mschema.ChangeChanged, fieldPath, "type changed from '%s' to '%s'", fieldType(oldFields[name]), fieldType(newField))
+		case !oldFields[name].Required && newField.Required:
+			d.report(mschema.ChangeBreaking, mschema.ChangeChanged, fieldPath, "became required")
+		case oldFields[name].Required && !newField.Required:
+			d.report(mschema.ChangeSafe, mschema.ChangeChanged, fieldPath, "became optional")
+		}
+	}
+	for _, name := range newNames {
+		if _, found := oldFields[name]; found {
+			continue
+		}
+		if newFields[name].Required {
+			d.report(mschema.ChangeBreaking, mschema.ChangeAdded, fmt.Sprintf(path, name), "required %s was added", what)
+		} else {
+			d.report(mschema.ChangeSafe, mschema.ChangeAdded, fmt.Sprintf(path, name), "optional %s was added", what)
+		}
+	}
+}
+
+// Compares the fields of types, which clients read
+func (d *schemaDiffer) outputFields(typeName string, old *[]Field, new *[]Field) {
+	oldNames, oldFields := fieldsByName(old)
+	newNames, newFields := fieldsByName(new)
+
+	for _, name := range oldNames {
+		path := fmt.Sprintf("%s.%s", typeName, name)
+		oldField := oldFields[name]
+		newField, found := newFields[name]
+		if !found {
+			d.report(mschema.ChangeBreaking, mschema.ChangeRemoved, path, "field was removed")
+			continue
+		}
+		switch {
+		case oldField.Type != newField.Type:
+			d.report(mschema.ChangeBreaking, mschema.ChangeChanged, path, "type changed from '%s' to '%s'", fieldType(oldField), fieldType(newField))
+		case oldField.Required && !newField.Required:
+			d.report(mschema.ChangeDangerous, mschema.ChangeChanged, path, "became nullable")
+		case !oldField.Required && newField.Required:
+			d.report(mschema.ChangeSafe, mschema.ChangeChanged, path, "became non-null")
+		}
+		if !isDeprecated(oldField.Element) && isDeprecated(newField.Element) {
+			d.report(mschema.ChangeDangerous, mschema.ChangeChanged, path, "became deprecated")
+		}
+		d.inputFields(path+"(%s)", oldField.Arguments, newField.Arguments, "argument")
+	}
+	for _, name := range newNames {
+		if _, found := oldFields[name]; !found {
+			d.report(mschema.ChangeSafe, mschema.ChangeAdded, fmt.Sprintf("%s.%s", typeName, name), "field was added")
+		}
+	}
+}
+
+func (d *schemaDiffer) enumValues(typeName string, old *[]Field, new *[]Field) {
+	oldNames, oldValues := fieldsByName(old)
+	newNames, newValues := fieldsByName(new)
+
+	for _, name := range oldNames {
+		if _, found := newValues[name]; !found {
+			d.report(mschema.ChangeBreaking, mschema.ChangeRemoved, fmt.Sprintf("%s.%s", typeName, name), "enum value was removed")
+		}
+	}
+	for _, name := range newNames {
+		if _, found := oldValues[name]; !found {
+			d.report(mschema.ChangeDangerous, mschema.ChangeAdded, fmt.Sprintf("%s.%s", typeName, name), "enum value was added")
+		}
+	}
+}
+
+func (d *schemaDiffer) definition(old Definition, new Definition) {
+	if old.Type != new.Type {
+		d.report(mschema.ChangeBreaking, mschema.ChangeChanged, old.Name, "changed from %s to %s", old.Type, new.Type)
+		return
+	}
+	switch old.Type {
+	case "type":
+		d.outputFields(old.Name, old.Fields, new.Fields)
+	case "input":
+		d.inputFields(old.Name+".%s", old.Fields, new.Fields, "input field")
+	case "enum":
+		d.enumValues(old.Name, old.Fields, new.Fields)
+	}
+}
+
+// Compares two generated GraphQL schemas by what clients send and receive, with the paths of the changes being the
+// coordinates of the schema, e.g. "Query.people(filter)". Directives other than deprecations aren't compared.
+func Diff(old *Schema, new *Schema) []mschema.Change {
+	d := &schemaDiffer{changes: []mschema.Change{}}
+
+	d.definition(old.Query, new.Query)
+	d.definition(old.Mutation, new.Mutation)
+
+	newTypes := make(map[string]Definition)
+	for _, def := range new.Types {
+		newTypes[def.Name] = def
+	}
+	oldTypes := make(map[string]bool)
+	for _, def := range old.Types {
+		oldTypes[def.Name] = true
+		if newDef, found := newTypes[def.Name]; found {
+			d.definition(def, newDef)
+		} else {
+			d.report(mschema.ChangeBreaking, mschema.ChangeRemoved, def.Name, "%s was removed", def.Type)
+		}
+	}
+	for _, def := range new.Types {
+		if !oldTypes[def.Name] {
+			d.report(mschema.ChangeSafe, mschema.ChangeAdded, def.Name, "%s was added", def.Type)
+		}
+	}
+
+	return d.changes
+}
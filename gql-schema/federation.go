@@ -0,0 +1,117 @@
+package gqlschema
+
+import (
+	"fmt"
+	"strings"
+
+	mschema "github.com/kinvey/odata-schema/mediation-schema"
+)
+
+const federationURL = "https://specs.apollo.dev/federation/v2.0"
+
+// Types the subgraph resolves entities and serves its schema with, which the service can't define itself
+var federationTypeNames = []string{"_Any", "_Entity", "_Service"}
+
+// Selects the fields of the paths, nesting the ones into structured properties, e.g. "orderId address { city }"
+func selectFields(paths [][]string, fieldName func(string) string, names *namer) string {
+	selected := []string{}
+	nested := make(map[string][][]string)
+	for _, path := range paths {
+		name := fieldName(path[0])
+		if _, found := nested[name]; !found {
+			selected = append(selected, name)
+			nested[name] = [][]string{}
+		}
+		if len(path) > 1 {
+			nested[name] = append(nested[name], path[1:])
+		}
+	}
+
+	selections := make([]string, 0, len(selected))
+	for _, name := range selected {
+		if len(nested[name]) == 0 {
+			selections = append(selections, name)
+		} else {
+			selections = append(selections, fmt.Sprintf("%s { %s }", name, selectFields(nested[name], names.fieldName, names)))
+		}
+	}
+	return strings.Join(selections, " ")
+}
+
+// The fields of the key of the entity type, composite keys and keys in structured properties included.
+// The fields follow the order of the sorted property names.
+func keyFields(entityType *mschema.EntityType, fields []Field, names *namer) string {
+	fieldNames := make(map[string]string)
+	for i, propName := range sortedPropertyNames(entityType.Properties) {
+		fieldNames[propName] = fields[i].Name
+	}
+	fieldName := func(propName string) string {
+		if name, found := fieldNames[propName]; found {
+			return name
+		}
+		return names.fieldName(propName)
+	}
+
+	paths := make([][]string, 0, len(entityType.Key))
+	for _, key := range entityType.Key {
+		paths = append(paths, strings.Split(key, "/"))
+	}
+	return selectFields(paths, fieldName, names)
+}
+
+func isEntity(def Definition) bool {
+	if def.Directives == nil {
+		return false
+	}
+	for _, directive := range *def.Directives {
+		if directive.Name == "key" {
+			return true
+		}
+	}
+	return false
+}
+
+// Links the schema to the federation specification and adds the types and queries subgraphs serve,
+// resolving the entities of the types with keys
+func addFederation(schema *Schema) {
+	schema.Links = append(schema.Links, SchemaLink{URL: federationURL, Imports: []string{"@key", "@shareable"}})
+
+	entities := []Field{}
+	for _, def := range schema.Types {
+		if def.Type == "type" && isEntity(def) {
+			entities = append(entities, Field{Element: Element{Name: def.Name}})
+		}
+	}
+
+	schema.Types = append(schema.Types,
+		Definition{Type: "scalar", Element: Element{Name: "_Any"}},
+		Definition{
+			Type:    "type",
+			Fields:  &[]Field{{Type: "String", Element: Element{Name: "sdl"}}},
+			Element: Element{Name: "_Service"},
+		},
+	)
+	queries := append(*schema.Query.Fields, Field{
+		Type:     "_Service",
+		Required: true,
+		Element:  Element{Name: "_service"},
+	})
+
+	// Subgraphs without entities have nothing to resolve
+	if len(entities) > 0 {
+		schema.Types = append(schema.Types, Definition{Type: "union", Fields: &entities, Element: Element{Name: "_Entity"}})
+		queries = append(queries, Field{
+			Type:     "[_Entity]",
+			Required: true,
+			Arguments: &[]Field{
+				{
+					Type:     "[_Any!]",
+					Required: true,
+					Element:  Element{Name: "representations"},
+				},
+			},
+			Element: Element{Name: "_entities"},
+		})
+	}
+	schema.Query.Fields = &queries
+}
@@ -3,6 +3,8 @@ package gqlschema
 import (
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	mschema "github.com/kinvey/odata-schema/mediation-schema"
@@ -11,45 +13,88 @@ import (
 
 var replaceLastDigitsRegexp = regexp.MustCompile(`\d+$`)
 
+// Single keys become IDs. Composite keys keep the types of their properties.
 func addKey(entityType *mschema.EntityType, fieldsRef *[]Field) {
-	if len(entityType.Key) > 1 {
-		panic("how do we handle composite keys")
+	if len(entityType.Key) != 1 {
+		return
 	}
 
 	fields := *fieldsRef
 
-	for i, field := range fields {
-		if entityType.Key[0] == field.Name {
+	// The fields follow the order of the sorted property names
+	for i, propName := range sortedPropertyNames(entityType.Properties) {
+		if entityType.Key[0] == propName {
 			fields[i].Type = "ID"
 			fields[i].Required = false // So we omit the ! in the schema
 		}
 	}
 }
 
+// Property a key refers to, following the paths of keys into structures
+func keyProperty(properties map[string]mschema.Property, key string, names *namer) (mschema.Property, bool) {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		prop, found := properties[segment]
+		if !found {
+			return mschema.Property{}, false
+		}
+		if i == len(segments)-1 {
+			return prop, true
+		}
+		def, found := names.types[prop.Type]
+		if !found || def.Structure == nil {
+			return mschema.Property{}, false
+		}
+		properties = def.Structure.Properties
+	}
+	return mschema.Property{}, false
+}
+
+// Arguments identifying an entity by its key. A single key is passed as the id, like the ID field of the entity type,
+// while composite keys take each of their properties with its own type, e.g. "Address_City" for "Address/City".
+func keyArguments(entityType *mschema.EntityType, names *namer) []Field {
+	if len(entityType.Key) == 1 {
+		return []Field{{Type: "ID", Required: true, Element: Element{Name: "id"}}}
+	}
+
+	arguments := []Field{}
+	usedNames := make(map[string]bool)
+	for _, key := range entityType.Key {
+		argType := "String"
+		if prop, found := keyProperty(entityType.Properties, key, names); found {
+			argType = propertyToFieldType(prop, names)
+		}
+		arguments = append(arguments, Field{
+			Type:     argType,
+			Required: true,
+			Element:  newNamedElement(uniqueName(names.fieldName(strings.ReplaceAll(key, "/", "_")), usedNames), key),
+		})
+	}
+	return arguments
+}
+
 func getName(def mschema.Type) string {
 	switch def.Kind {
 	default:
 		panic("should not happen")
-	case "EntityType":
+	case mschema.TypeKindEntityType:
 		return def.EntityType.Name
-	case "Structure":
+	case mschema.TypeKindStructure:
 		return def.Structure.Name
-	case "Enum":
+	case mschema.TypeKindEnum:
 		return def.Enum.Name
 	}
 }
 
-func getTypeName(typeName string, types map[string]mschema.Type) string {
-	return getName(types[typeName])
-}
-
 func typeToArray(typeName string) string {
 	return fmt.Sprintf("[%s]", typeName)
 }
 
-func propertyToFieldType(prop mschema.Property, types map[string]mschema.Type) string {
+func propertyToFieldType(prop mschema.Property, names *namer) string {
 	var fieldType string
-	if prop.Kind == "primitive" {
+	if prop.TypeDefinition != nil && names.options.CustomScalars {
+		fieldType = names.scalarName(*prop.TypeDefinition)
+	} else if prop.Kind == mschema.PropertyKindPrimitive {
 		fieldType = strings.Title(prop.Type)
 		if strings.HasPrefix(fieldType, "Int") || strings.HasPrefix(fieldType, "Float") {
 			fieldType = replaceLastDigitsRegexp.ReplaceAllString(fieldType, "")
@@ -60,7 +105,12 @@ func propertyToFieldType(prop mschema.Property, types map[string]mschema.Type) s
 			fieldType = "Float"
 		}
 	} else {
-		fieldType = getTypeName(prop.Type, types)
+		fieldType = names.typeName(prop.Type)
+	}
+
+	// Values of flags enums combine several members, so they're lists of the members
+	if def, found := names.types[prop.Type]; found && prop.Kind == mschema.PropertyKindEnum && def.Enum != nil && def.Enum.Multiselect {
+		fieldType = typeToArray(fieldType)
 	}
 
 	if prop.IsCollection {
@@ -70,35 +120,130 @@ func propertyToFieldType(prop mschema.Property, types map[string]mschema.Type) s
 	return fieldType
 }
 
-func propToField(propName string, prop mschema.Property, types map[string]mschema.Type) Field {
-	field := Field{
-		Type:     propertyToFieldType(prop, types),
-		Required: prop.Required,
+func newExpandArgument(name string, argType string, option string) Field {
+	return Field{
+		Type: argType,
 		Element: Element{
-			Name: propName,
+			Name:       name,
+			Directives: &[]Directive{newExpandDirective(option)},
 		},
 	}
+}
+
+// Arguments of collection-valued navigation fields, passed on as nested $expand options
+func navigationArguments() *[]Field {
+	return &[]Field{
+		newExpandArgument("filter", "String", "$filter"),
+		newExpandArgument("sort", "String", "$orderby"),
+		newExpandArgument("top", "Int", "$top"),
+		newExpandArgument("skip", "Int", "$skip"),
+	}
+}
+
+func relationToConnection(propName string, prop mschema.Property) Directive {
+	navigation := propName
+	if prop.NavigationPath != nil {
+		navigation = *prop.NavigationPath
+	}
+
+	collection := ""
+	if prop.RelationCollection != nil {
+		collection = *prop.RelationCollection
+	}
+
+	primaryKeys := []string{}
+	foreignKeys := []string{}
+	for _, constraint := range prop.ReferentialConstraints {
+		primaryKeys = append(primaryKeys, constraint.Property)
+		foreignKeys = append(foreignKeys, constraint.ReferencedProperty)
+	}
+
+	return newConnectionDirective(navigation, collection, strings.Join(primaryKeys, ","), strings.Join(foreignKeys, ","))
+}
+
+// Names the element after the OData one, recording the original name when it had to be changed
+func newNamedElement(name string, originalName string) Element {
+	element := Element{Name: name}
+	if name != originalName {
+		element.Directives = &[]Directive{newOriginalNameDirective(originalName)}
+	}
+	return element
+}
+
+func appendDirective(element *Element, directive Directive) {
+	directives := []Directive{directive}
+	if element.Directives != nil {
+		directives = append(*element.Directives, directive)
+	}
+	element.Directives = &directives
+}
+
+func removeDirective(element *Element, name string) {
+	if element.Directives == nil {
+		return
+	}
+	directives := []Directive{}
+	for _, directive := range *element.Directives {
+		if directive.Name != name {
+			directives = append(directives, directive)
+		}
+	}
+	element.Directives = nil
+	if len(directives) > 0 {
+		element.Directives = &directives
+	}
+}
+
+func propToField(fieldName string, propName string, prop mschema.Property, names *namer) Field {
+	field := Field{
+		Type:     propertyToFieldType(prop, names),
+		Required: prop.Required,
+		Element:  newNamedElement(fieldName, propName),
+	}
+	field.Description = prop.Description
+
+	if prop.Kind == mschema.PropertyKindRelation {
+		appendDirective(&field.Element, relationToConnection(propName, prop))
+		if prop.IsCollection {
+			field.Arguments = navigationArguments()
+		}
+	}
+
+	if prop.Deprecation != nil {
+		appendDirective(&field.Element, newDeprecatedDirective(*prop.Deprecation))
+	}
 
 	return field
 }
 
-func propsToFields(properties map[string]mschema.Property, types map[string]mschema.Type) *[]Field {
+func sortedPropertyNames(properties map[string]mschema.Property) []string {
+	propNames := make([]string, 0, len(properties))
+	for propName := range properties {
+		propNames = append(propNames, propName)
+	}
+	sort.Strings(propNames)
+	return propNames
+}
+
+func propsToFields(properties map[string]mschema.Property, names *namer) *[]Field {
 	fields := make([]Field, len(properties))
-	i := 0
-	for propName, prop := range properties {
-		fields[i] = propToField(propName, prop, types)
-		i += 1
+	usedNames := make(map[string]bool)
+	for i, propName := range sortedPropertyNames(properties) {
+		fieldName := uniqueName(names.fieldName(propName), usedNames)
+		fields[i] = propToField(fieldName, propName, properties[propName], names)
 	}
 
 	return &fields
 }
 
-func createDefinition(name string, properties map[string]mschema.Property, types map[string]mschema.Type) Definition {
+func createDefinition(qualifiedName string, structure *mschema.Structure, properties map[string]mschema.Property, names *namer) Definition {
 	def := Definition{
 		Type:   "type",
-		Fields: propsToFields(properties, types),
+		Fields: propsToFields(properties, names),
 		Element: Element{
-			Name: name,
+			Name:        names.typeName(qualifiedName),
+			Description: structure.Description,
+			Directives:  &[]Directive{newOriginalNameDirective(qualifiedName)},
 		},
 	}
 
@@ -109,84 +254,641 @@ func getInputTypeName(entityTypeName string) string {
 	return fmt.Sprintf("%sInput", entityTypeName)
 }
 
-func createInputType(entityType *mschema.EntityType, types map[string]mschema.Type) Definition {
-	typeDef := createDefinition(entityType.Name, entityType.Properties, types)
+func getUpdateInputTypeName(entityTypeName string) string {
+	return fmt.Sprintf("%sUpdateInput", entityTypeName)
+}
+
+// Clients can't set the computed and read-only properties
+func isWritable(prop mschema.Property) bool {
+	return !prop.Computed && !prop.ReadOnly
+}
+
+// Immutable properties can be set on creation, but not on update, which then needs an input of its own
+func hasImmutableProperties(entityType *mschema.EntityType) bool {
+	for _, prop := range entityType.Properties {
+		if prop.Kind != mschema.PropertyKindRelation && isWritable(prop) && prop.Immutable {
+			return true
+		}
+	}
+	return false
+}
+
+func createInputType(entityTypeName string, entityType *mschema.EntityType, forUpdate bool, names *namer) Definition {
+	structuralProps := make(map[string]mschema.Property)
+	for propName, prop := range entityType.Properties {
+		if prop.Kind != mschema.PropertyKindRelation && isWritable(prop) && !(forUpdate && prop.Immutable) {
+			structuralProps[propName] = prop
+		}
+	}
+
+	typeDef := createDefinition(entityTypeName, &entityType.Structure, structuralProps, names)
 	typeDef.Type = "input"
-	typeDef.Name = getInputTypeName(typeDef.Name)
+	if forUpdate {
+		typeDef.Name = getUpdateInputTypeName(typeDef.Name)
+	} else {
+		typeDef.Name = getInputTypeName(typeDef.Name)
+	}
+	fields := *typeDef.Fields
+	for i := range fields {
+		fields[i].Required = false
+		// Input fields can't be deprecated
+		removeDirective(&fields[i].Element, "deprecated")
+	}
+	return typeDef
+}
+
+func argumentToFieldType(prop mschema.Property, names *namer) string {
+	if prop.Kind != mschema.PropertyKindStructure && prop.Kind != mschema.PropertyKindRelation {
+		return propertyToFieldType(prop, names)
+	}
+
+	fieldType := getInputTypeName(names.typeName(prop.Type))
+	if prop.IsCollection {
+		fieldType = typeToArray(fieldType)
+	}
+
+	return fieldType
+}
+
+func isRepresentable(prop mschema.Property, types map[string]mschema.Type) bool {
+	if prop.Kind == mschema.PropertyKindPrimitive {
+		return true
+	}
+	_, found := types[prop.Type]
+	return found
+}
+
+func createStructureInputType(structureName string, structure *mschema.Structure, names *namer) Definition {
 	fields := []Field{}
-	for _, field := range *typeDef.Fields {
-		isStructuralProp := entityType.Properties[field.Name].Kind != "relation"
-		if field.Type != "ID" && isStructuralProp {
-			field.Required = false
-			fields = append(fields, field)
+	usedNames := make(map[string]bool)
+	for _, propName := range sortedPropertyNames(structure.Properties) {
+		prop := structure.Properties[propName]
+		if prop.Kind == mschema.PropertyKindRelation || !isWritable(prop) || !isRepresentable(prop, names.types) {
+			continue
+		}
+		field := Field{
+			Type:     argumentToFieldType(prop, names),
+			Required: prop.Required,
+			Element:  newNamedElement(uniqueName(names.fieldName(propName), usedNames), propName),
 		}
+		field.Description = prop.Description
+		fields = append(fields, field)
 	}
-	typeDef.Fields = &fields
-	return typeDef
+
+	return Definition{
+		Type:   "input",
+		Fields: &fields,
+		Element: Element{
+			Name:        getInputTypeName(names.typeName(structureName)),
+			Description: structure.Description,
+			Directives:  &[]Directive{newOriginalNameDirective(structureName)},
+		},
+	}
+}
+
+// Collects the complex types which need an input type when passed as arguments, including the nested ones
+func collectStructureInputs(prop mschema.Property, types map[string]mschema.Type, collected map[string]bool) {
+	if prop.Kind != mschema.PropertyKindStructure || collected[prop.Type] {
+		return
+	}
+
+	collected[prop.Type] = true
+	for _, nestedProp := range types[prop.Type].Structure.Properties {
+		collectStructureInputs(nestedProp, types, collected)
+	}
+}
+
+func invocationArgumentsToFields(arguments []mschema.InvocationArgument, names *namer) []Field {
+	fields := make([]Field, len(arguments))
+	usedNames := make(map[string]bool)
+	for i, arg := range arguments {
+		fields[i] = Field{
+			Type:     argumentToFieldType(arg.Property, names),
+			Required: arg.Required,
+			Element:  newNamedElement(uniqueName(names.fieldName(arg.Name), usedNames), arg.Name),
+		}
+		fields[i].Description = arg.Description
+	}
+	return fields
+}
+
+func invocationToField(name string, inv mschema.Invocation, arguments []Field, directive Directive, names *namer) Field {
+	// GraphQL has no void type, so invocations without results return a Boolean which is always null
+	field := Field{
+		Type: "Boolean",
+		Element: Element{
+			Name:        name,
+			Description: inv.Description,
+			Directives:  &[]Directive{directive},
+		},
+	}
+
+	if inv.Result != nil {
+		field.Type = propertyToFieldType(*inv.Result, names)
+		field.Required = inv.Result.Required
+	}
+
+	if len(arguments) > 0 {
+		field.Arguments = &arguments
+	}
+
+	if inv.Deprecation != nil {
+		appendDirective(&field.Element, newDeprecatedDirective(*inv.Deprecation))
+	}
+
+	return field
+}
+
+func isInvocationRepresentable(inv mschema.Invocation, types map[string]mschema.Type) bool {
+	representable := inv.Result == nil || isRepresentable(*inv.Result, types)
+	for _, arg := range inv.Arguments {
+		representable = representable && isRepresentable(arg.Property, types)
+	}
+	return representable
+}
+
+func getInvocationMethod(inv mschema.Invocation) string {
+	if inv.Kind == mschema.InvocationKindAction {
+		return "POST"
+	}
+	return "GET"
+}
+
+func getResultCollection(inv mschema.Invocation) string {
+	if inv.ResultCollection != nil {
+		return *inv.ResultCollection
+	} else if inv.Result != nil && inv.Result.RelationCollection != nil {
+		return *inv.Result.RelationCollection
+	}
+	return ""
+}
+
+// Name of the backend the requests for the element go to
+func backendProduct(service *mschema.Service, provenance *mschema.Provenance) string {
+	if provenance != nil {
+		return provenance.Backend
+	}
+	if service.Backend != "" {
+		return service.Backend
+	}
+	return service.Name
+}
+
+// Name the backend knows the element by, which differs when merging the backends prefixed it
+func backendName(name string, provenance *mschema.Provenance) string {
+	if provenance != nil && provenance.Name != nil {
+		return *provenance.Name
+	}
+	return name
+}
+
+func backendCollectionName(service *mschema.Service, name string) string {
+	if collection, found := service.Collections[name]; found {
+		return backendName(name, collection.Provenance)
+	}
+	return name
+}
+
+func sortedInvocationNames(invocations map[string]mschema.Invocation) []string {
+	names := make([]string, 0, len(invocations))
+	for name := range invocations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// The entity type followed by the types it derives from, up the chain of its base types
+func entityTypeChain(entityTypeName string, types map[string]mschema.Type) []string {
+	chain := []string{}
+	for typeName := &entityTypeName; typeName != nil && !utils.SliceContainsString(chain, *typeName); {
+		chain = append(chain, *typeName)
+		def, found := types[*typeName]
+		if !found || def.EntityType == nil {
+			break
+		}
+		typeName = def.EntityType.BaseType
+	}
+	return chain
+}
+
+// Functions bound to a single entity become fields of the entity type, taking the rest of the parameters as arguments.
+// The functions bound to the types it derives from apply to it as well.
+func boundFunctionsToFields(entityTypeName string, typeFields []Field, service *mschema.Service, names *namer) []Field {
+	fields := []Field{}
+	addedFields := make(map[string]bool)
+	for _, field := range typeFields {
+		addedFields[field.Name] = true
+	}
+
+	// Types are compared by their GraphQL names, which are the same for the namespaced and aliased names of a type
+	boundTypes := []string{}
+	for _, typeName := range entityTypeChain(entityTypeName, service.Types) {
+		boundTypes = append(boundTypes, names.typeName(typeName))
+	}
+	addedInvocations := make(map[string]bool)
+	for _, name := range sortedInvocationNames(service.Invocations) {
+		inv := service.Invocations[name]
+		if inv.Kind != mschema.InvocationKindFunction || inv.BindingType != mschema.BindingEntity {
+			continue
+		}
+		if inv.BoundTo == nil || !utils.SliceContainsString(boundTypes, names.typeName(*inv.BoundTo)) {
+			continue
+		}
+		// Aliased and namespaced names point to the same invocation
+		invocationKey := fmt.Sprintf("%s/%s", names.typeName(*inv.BoundTo), inv.Name)
+		if addedInvocations[invocationKey] {
+			continue
+		}
+		addedInvocations[invocationKey] = true
+		if !isInvocationRepresentable(inv, service.Types) {
+			names.warn("Skipping invocation '%s' as some of its types are not defined", name)
+			continue
+		}
+
+		if len(inv.Arguments) == 0 {
+			names.warn("Skipping invocation '%s' as it has no binding parameter", name)
+			continue
+		}
+
+		fieldName := uniqueName(utils.LowerFirstLetter(names.fieldName(inv.Name)), addedFields)
+		directive := newBackendDirective(backendProduct(service, inv.Provenance), backendCollectionName(service, getResultCollection(inv)), "GET", backendName(name, inv.Provenance))
+		arguments := invocationArgumentsToFields(inv.Arguments[1:], names)
+		fields = append(fields, invocationToField(fieldName, inv, arguments, directive, names))
+	}
+
+	return fields
+}
+
+// Links from the collections of the entity type become fields of the entity type, joined by the backend of the target collection
+func linksToFields(entityTypeName string, typeFields []Field, service *mschema.Service, names *namer) []Field {
+	fields := []Field{}
+	addedFields := make(map[string]bool)
+	for _, field := range typeFields {
+		addedFields[field.Name] = true
+	}
+
+	for _, link := range service.Links {
+		if service.Collections[link.SourceCollection].EntityType != entityTypeName {
+			continue
+		}
+		target, found := service.Collections[link.TargetCollection]
+		if !found {
+			names.warn("Skipping link '%s' as its target collection '%s' is not defined", link.Name, link.TargetCollection)
+			continue
+		}
+
+		fieldName := names.fieldName(link.Name)
+		if addedFields[fieldName] {
+			names.warn("Skipping link '%s' as type '%s' already has a field '%s'", link.Name, entityTypeName, fieldName)
+			continue
+		}
+		addedFields[fieldName] = true
+
+		fieldType := names.typeName(target.EntityType)
+		if link.IsCollection {
+			fieldType = typeToArray(fieldType)
+		}
+		field := Field{
+			Type:    fieldType,
+			Element: newNamedElement(fieldName, link.Name),
+		}
+		field.Description = link.Description
+		appendDirective(&field.Element, newJoinDirective(backendProduct(service, target.Provenance), backendCollectionName(service, link.TargetCollection), link.SourceProperty, link.TargetProperty))
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+// Functions bound to a collection become queries and bound actions become mutations on each collection of the bound type.
+// Actions bound to a single entity take its key as the first arguments.
+func boundInvocationsToFields(service *mschema.Service, names *namer, rootNames map[string]string) ([]Field, []Field, error) {
+	queries := []Field{}
+	mutations := []Field{}
+	addedFields := make(map[string]bool)
+
+	for _, name := range sortedInvocationNames(service.Invocations) {
+		inv := service.Invocations[name]
+		isCollectionFunction := inv.Kind == mschema.InvocationKindFunction && inv.BindingType == mschema.BindingCollection
+		isBoundAction := inv.Kind == mschema.InvocationKindAction && (inv.BindingType == mschema.BindingEntity || inv.BindingType == mschema.BindingCollection)
+		if !isCollectionFunction && !isBoundAction {
+			continue
+		}
+		if !isInvocationRepresentable(inv, service.Types) {
+			names.warn("Skipping invocation '%s' as some of its types are not defined", name)
+			continue
+		}
+
+		if len(inv.Arguments) == 0 || inv.BoundTo == nil {
+			names.warn("Skipping invocation '%s' as it has no binding parameter", name)
+			continue
+		}
+
+		if inv.BindingType == mschema.BindingEntity {
+			if boundType, found := names.types[*inv.BoundTo]; found && boundType.EntityType != nil && len(boundType.EntityType.Key) == 0 {
+				names.warn("Skipping invocation '%s' as its binding type '%s' has no key", name, *inv.BoundTo)
+				continue
+			}
+		}
+
+		collectionNames := []string{}
+		for _, collectionName := range sortedCollectionNames(service.Collections) {
+			if service.Collections[collectionName].EntityType == *inv.BoundTo {
+				collectionNames = append(collectionNames, collectionName)
+			}
+		}
+		if len(collectionNames) == 0 {
+			names.warn("Skipping invocation '%s' as there is no collection for its binding type '%s'", name, *inv.BoundTo)
+			continue
+		}
+
+		for _, collectionName := range collectionNames {
+			fieldName := inv.Name
+			if isCollectionFunction || len(collectionNames) > 1 {
+				fieldName = fmt.Sprintf("%s%s", collectionName, inv.Name)
+			}
+			fieldName = uniqueName(utils.LowerFirstLetter(names.fieldName(fieldName)), addedFields)
+
+			arguments := invocationArgumentsToFields(inv.Arguments[1:], names)
+			if inv.BindingType == mschema.BindingEntity {
+				arguments = append(keyArguments(names.types[*inv.BoundTo].EntityType, names), arguments...)
+			}
+
+			directive := newBackendDirective(backendProduct(service, inv.Provenance), backendCollectionName(service, collectionName), getInvocationMethod(inv), backendName(name, inv.Provenance))
+			field := invocationToField(fieldName, inv, arguments, directive, names)
+			if err := registerInvocationField(&field, inv, fmt.Sprintf("invocation '%s'", name), rootNames); err != nil {
+				return nil, nil, err
+			}
+			if isCollectionFunction {
+				queries = append(queries, field)
+			} else {
+				mutations = append(mutations, field)
+			}
+		}
+	}
+
+	return queries, mutations, nil
+}
+
+// Unbound functions are exposed as queries and unbound actions as mutations.
+// Also returns the input types of the complex types used as arguments of unbound and bound invocations.
+func unboundInvocationsToFields(service *mschema.Service, names *namer, rootNames map[string]string) ([]Field, []Field, []Definition, error) {
+	queries := []Field{}
+	mutations := []Field{}
+	addedFields := make(map[string]bool)
+	addedInvocations := make(map[string]bool)
+	structureInputs := make(map[string]bool)
+
+	for _, name := range sortedInvocationNames(service.Invocations) {
+		inv := service.Invocations[name]
+		if inv.BindingType != mschema.BindingUnbound {
+			continue
+		}
+		if !isInvocationRepresentable(inv, service.Types) {
+			names.warn("Skipping invocation '%s' as some of its types are not defined", name)
+			continue
+		}
+
+		endpoint := inv.Name
+		if inv.ImportName != nil {
+			endpoint = *inv.ImportName
+		}
+		// Aliased and namespaced names point to the same invocation
+		if addedInvocations[endpoint] {
+			continue
+		}
+		addedInvocations[endpoint] = true
+
+		directive := newBackendDirective(backendProduct(service, inv.Provenance), backendCollectionName(service, getResultCollection(inv)), getInvocationMethod(inv), backendName(endpoint, inv.Provenance))
+		arguments := invocationArgumentsToFields(inv.Arguments, names)
+		fieldName := uniqueName(utils.LowerFirstLetter(names.fieldName(endpoint)), addedFields)
+		field := invocationToField(fieldName, inv, arguments, directive, names)
+		if err := registerInvocationField(&field, inv, fmt.Sprintf("invocation '%s'", name), rootNames); err != nil {
+			return nil, nil, nil, err
+		}
+
+		if inv.Kind == mschema.InvocationKindAction {
+			mutations = append(mutations, field)
+		} else {
+			queries = append(queries, field)
+		}
+
+		for _, arg := range inv.Arguments {
+			collectStructureInputs(arg.Property, service.Types, structureInputs)
+		}
+	}
+
+	for _, inv := range service.Invocations {
+		if inv.BindingType == mschema.BindingEntity || inv.BindingType == mschema.BindingCollection {
+			if len(inv.Arguments) == 0 {
+				continue
+			}
+			for _, arg := range inv.Arguments[1:] {
+				collectStructureInputs(arg.Property, service.Types, structureInputs)
+			}
+		}
+	}
+
+	inputNames := make([]string, 0, len(structureInputs))
+	for name := range structureInputs {
+		inputNames = append(inputNames, name)
+	}
+	sort.Strings(inputNames)
+
+	inputs := []Definition{}
+	addedInputs := make(map[string]bool)
+	for _, name := range inputNames {
+		input := createStructureInputType(name, service.Types[name].Structure, names)
+		if !addedInputs[input.Name] {
+			addedInputs[input.Name] = true
+			inputs = append(inputs, input)
+		}
+	}
+
+	return queries, mutations, inputs, nil
+}
+
+// Collections without capabilities support everything
+func getCapabilities(collection *mschema.Collection) mschema.Capabilities {
+	if collection.Capabilities == nil {
+		return mschema.Capabilities{
+			Insertable: true,
+			Updatable:  true,
+			Deletable:  true,
+			Filterable: true,
+			Sortable:   true,
+			Expandable: true,
+			Countable:  true,
+		}
+	}
+	return *collection.Capabilities
+}
+
+// Drops the relation fields the collection doesn't allow to expand. The fields follow the order of the sorted property names.
+func removeNonExpandableFields(entityType *mschema.EntityType, capabilities mschema.Capabilities, fieldsRef *[]Field) {
+	fields := []Field{}
+	for i, propName := range sortedPropertyNames(entityType.Properties) {
+		prop := entityType.Properties[propName]
+		if prop.Kind == mschema.PropertyKindRelation && (!capabilities.Expandable || utils.SliceContainsString(capabilities.NonExpandableProperties, propName)) {
+			continue
+		}
+		fields = append(fields, (*fieldsRef)[i])
+	}
+	*fieldsRef = fields
+}
+
+func sortedCollectionNames(collections map[string]mschema.Collection) []string {
+	names := make([]string, 0, len(collections))
+	for name := range collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
+// The first collection of the entity type by name, so that types of several collections always get the same one
 func findCollectionForType(entityTypeName string, collections map[string]mschema.Collection) (string, bool) {
-	for name, collection := range collections {
-		if collection.EntityType == entityTypeName {
+	for _, name := range sortedCollectionNames(collections) {
+		if collections[name].EntityType == entityTypeName {
 			return name, true
 		}
 	}
 	return "", false
 }
 
-func entityTypeToDefinition(entityTypeName string, service *mschema.Service) (Definition, Definition) {
+func entityTypeToDefinition(entityTypeName string, service *mschema.Service, names *namer) (Definition, []Definition) {
 	entityType := service.Types[entityTypeName].EntityType
-	typeDef := createDefinition(entityType.Name, entityType.Properties, service.Types)
+	typeDef := createDefinition(entityTypeName, &entityType.Structure, entityType.Properties, names)
 
-	if collectionForType, found := findCollectionForType(entityTypeName, service.Collections); found {
-		typeDef.Directives = &[]Directive{newBackendDirective("sitefinity", collectionForType, "", "")}
-	}
 	addKey(entityType, typeDef.Fields)
-	inputDef := createInputType(entityType, service.Types)
-	return typeDef, inputDef
+	collectionForType, hasCollection := findCollectionForType(entityTypeName, service.Collections)
+	// Only the entities of collections can be fetched by their keys, the rest are values of other entities
+	if names.options.Federation && hasCollection && len(entityType.Key) > 0 {
+		appendDirective(&typeDef.Element, newKeyDirective(keyFields(entityType, *typeDef.Fields, names)))
+	} else if names.options.Federation {
+		appendDirective(&typeDef.Element, newShareableDirective())
+	}
+	if hasCollection {
+		collection := service.Collections[collectionForType]
+		directives := append(*typeDef.Directives, newBackendDirective(backendProduct(service, collection.Provenance), backendCollectionName(service, collectionForType), "", ""))
+		typeDef.Directives = &directives
+		removeNonExpandableFields(entityType, getCapabilities(&collection), typeDef.Fields)
+	}
+	boundFields := append(*typeDef.Fields, boundFunctionsToFields(entityTypeName, *typeDef.Fields, service, names)...)
+	linkFields := append(boundFields, linksToFields(entityTypeName, boundFields, service, names)...)
+	typeDef.Fields = &linkFields
+	inputDefs := []Definition{createInputType(entityTypeName, entityType, false, names)}
+	if hasImmutableProperties(entityType) {
+		inputDefs = append(inputDefs, createInputType(entityTypeName, entityType, true, names))
+	}
+	return typeDef, inputDefs
 }
 
-func createQueryFields(collection *mschema.Collection, service *mschema.Service) []Field {
-	entityTypeName := getName(service.Types[collection.EntityType])
-	fields := []Field{
-		{
-			Type: entityTypeName,
-			Arguments: &[]Field{
-				{
-					Type:     "ID",
-					Required: true,
-					Element:  Element{Name: "id"},
-				},
-			},
+func deprecateCollectionFields(collection *mschema.Collection, fields []Field) {
+	if collection.Deprecation == nil {
+		return
+	}
+	for i := range fields {
+		appendDirective(&fields[i].Element, newDeprecatedDirective(*collection.Deprecation))
+	}
+}
+
+// Arguments of the list query, restricted to what the collection supports
+func listArguments(capabilities mschema.Capabilities) *[]Field {
+	arguments := []Field{}
+	if capabilities.Filterable {
+		filterArg := Field{
+			Type:    "String",
+			Element: Element{Name: "filter"},
+		}
+		if len(capabilities.NonFilterableProperties) > 0 {
+			filterArg.Directives = &[]Directive{newUnsupportedPropertiesDirective(strings.Join(capabilities.NonFilterableProperties, ","))}
+		}
+		arguments = append(arguments, filterArg)
+	}
+	if capabilities.Sortable {
+		sortArg := Field{
+			Type:    "String",
+			Element: Element{Name: "sort"},
+		}
+		if len(capabilities.NonSortableProperties) > 0 {
+			sortArg.Directives = &[]Directive{newUnsupportedPropertiesDirective(strings.Join(capabilities.NonSortableProperties, ","))}
+		}
+		arguments = append(arguments, sortArg)
+	}
+
+	if len(arguments) == 0 {
+		return nil
+	}
+	return &arguments
+}
+
+func createQueryFields(collection *mschema.Collection, byCollection bool, product string, names *namer) []Field {
+	entityTypeName := names.typeName(collection.EntityType)
+	singleName, pluralName := names.collectionFieldNames(collection, byCollection)
+	capabilities := getCapabilities(collection)
+	fields := []Field{}
+	entityType := names.types[collection.EntityType].EntityType
+	if len(entityType.Key) > 0 {
+		keyArgs := keyArguments(entityType, names)
+		fields = append(fields, Field{
+			Type:      entityTypeName,
+			Arguments: &keyArgs,
 			Element: Element{
-				Name: utils.LowerFirstLetter(entityTypeName),
+				Name:        utils.LowerFirstLetter(singleName),
+				Description: collection.Description,
 			},
+		})
+	} else {
+		names.warn("Skipping the by-key field of collection '%s' as its entity type '%s' has no key", collection.Name, collection.EntityType)
+	}
+	fields = append(fields, Field{
+		Type:      typeToArray(entityTypeName),
+		Arguments: listArguments(capabilities),
+		Element: Element{
+			Name:        utils.LowerFirstLetter(pluralName),
+			Description: collection.Description,
 		},
-		{
-			Type: typeToArray(entityTypeName),
-			Arguments: &[]Field{
+	})
+
+	if capabilities.Countable {
+		count := Field{
+			Type: "Int",
+			Element: Element{
+				Name:       fmt.Sprintf("%sCount", utils.LowerFirstLetter(pluralName)),
+				Directives: &[]Directive{newBackendDirective(product, backendName(collection.Name, collection.Provenance), "GET", "$count")},
+			},
+		}
+		if capabilities.Filterable {
+			count.Arguments = &[]Field{
 				{
 					Type:    "String",
 					Element: Element{Name: "filter"},
 				},
-				{
-					Type:    "String",
-					Element: Element{Name: "sort"},
-				},
-			},
-			Element: Element{
-				Name: fmt.Sprintf("%ss", utils.LowerFirstLetter(entityTypeName)),
-			},
-		},
+			}
+		}
+		fields = append(fields, count)
 	}
 
+	deprecateCollectionFields(collection, fields)
 	return fields
 }
 
-func createMutationFields(collection *mschema.Collection, service *mschema.Service) []Field {
-	entityTypeName := getName(service.Types[collection.EntityType])
-	fields := []Field{
-		{
+func createMutationFields(collection *mschema.Collection, byCollection bool, product string, names *namer) []Field {
+	entityTypeName := names.typeName(collection.EntityType)
+	singleName, _ := names.collectionFieldNames(collection, byCollection)
+	capabilities := getCapabilities(collection)
+	fields := []Field{}
+
+	entityType := names.types[collection.EntityType].EntityType
+	updateInputTypeName := getInputTypeName(entityTypeName)
+	if hasImmutableProperties(entityType) {
+		updateInputTypeName = getUpdateInputTypeName(entityTypeName)
+	}
+
+	if capabilities.Insertable {
+		fields = append(fields, Field{
 			Type: entityTypeName,
 			Arguments: &[]Field{
 				{
@@ -196,100 +898,130 @@ func createMutationFields(collection *mschema.Collection, service *mschema.Servi
 				},
 			},
 			Element: Element{
-				Name:       fmt.Sprintf("add%s", utils.UpperFirstLetter(entityTypeName)),
-				Directives: &[]Directive{newBackendDirective("sitefinity", collection.Name, "POST", "")},
-			},
-		},
-		{
-			Type: "Boolean",
-			Arguments: &[]Field{
-				{
-					Type:     "ID",
-					Required: true,
-					Element:  Element{Name: "id"},
-				},
-				{
-					Type:     fmt.Sprintf("%sInput", entityTypeName),
-					Required: true,
-					Element:  Element{Name: "data"},
-				},
+				Name:       fmt.Sprintf("add%s", utils.UpperFirstLetter(singleName)),
+				Directives: &[]Directive{newBackendDirective(product, backendName(collection.Name, collection.Provenance), "POST", "")},
 			},
+		})
+	}
+
+	// Updating and removing address a single entity by its key
+	hasKey := len(entityType.Key) > 0
+	if (capabilities.Updatable || capabilities.Deletable) && !hasKey {
+		names.warn("Skipping the update and remove mutations of collection '%s' as its entity type '%s' has no key", collection.Name, collection.EntityType)
+	}
+
+	if capabilities.Updatable && hasKey {
+		updateArgs := append(keyArguments(entityType, names), Field{
+			Type:     updateInputTypeName,
+			Required: true,
+			Element:  Element{Name: "data"},
+		})
+		fields = append(fields, Field{
+			Type:      "Boolean",
+			Arguments: &updateArgs,
 			Element: Element{
-				Name:       fmt.Sprintf("update%s", utils.UpperFirstLetter(entityTypeName)),
-				Directives: &[]Directive{newBackendDirective("sitefinity", collection.Name, "PATCH", "")},
-			},
-		},
-		{
-			Type: "Boolean",
-			Arguments: &[]Field{
-				{
-					Type:     "ID",
-					Required: true,
-					Element:  Element{Name: "id"},
-				},
+				Name:       fmt.Sprintf("update%s", utils.UpperFirstLetter(singleName)),
+				Directives: &[]Directive{newBackendDirective(product, backendName(collection.Name, collection.Provenance), "PATCH", "")},
 			},
+		})
+	}
+
+	if capabilities.Deletable && hasKey {
+		removeArgs := keyArguments(entityType, names)
+		fields = append(fields, Field{
+			Type:      "Boolean",
+			Arguments: &removeArgs,
 			Element: Element{
-				Name:       fmt.Sprintf("remove%s", utils.UpperFirstLetter(entityTypeName)),
-				Directives: &[]Directive{newBackendDirective("sitefinity", collection.Name, "DELETE", "")},
+				Name:       fmt.Sprintf("remove%s", utils.UpperFirstLetter(singleName)),
+				Directives: &[]Directive{newBackendDirective(product, backendName(collection.Name, collection.Provenance), "DELETE", "")},
 			},
-		},
+		})
 	}
 
+	deprecateCollectionFields(collection, fields)
 	return fields
 }
 
-func enumMembersToFields(members map[string]string, membersType string) *[]Field {
+func enumMembersToFields(enum *mschema.Enum, names *namer) *[]Field {
 	elements := []Field{}
-	for memberName := range members {
-		elements = append(elements, Field{Element: Element{Name: memberName}})
+	usedNames := make(map[string]bool)
+	for _, member := range enum.Members {
+		element := newNamedElement(uniqueName(names.enumValueName(member.Name), usedNames), member.Name)
+		element.Description = member.Description
+		appendDirective(&element, newEnumValueDirective(strconv.FormatInt(member.Value, 10)))
+		if member.Deprecation != nil {
+			appendDirective(&element, newDeprecatedDirective(*member.Deprecation))
+		}
+		elements = append(elements, Field{Element: element})
 	}
 	return &elements
 }
 
-func enumToDefinition(enum *mschema.Enum) Definition {
-	fields := enumMembersToFields(enum.Members, enum.ValuesType)
+func enumToDefinition(enumName string, enum *mschema.Enum, names *namer) Definition {
+	fields := enumMembersToFields(enum, names)
 	return Definition{
-		Type:    "enum",
-		Element: Element{Name: enum.Name},
-		Fields:  fields,
+		Type: "enum",
+		Element: Element{
+			Name:        names.typeName(enumName),
+			Description: enum.Description,
+			Directives:  &[]Directive{newOriginalNameDirective(enumName)},
+		},
+		Fields: fields,
 	}
 }
 
-func typeDefToDefinition(service *mschema.Service) []Definition {
+func typeDefToDefinition(service *mschema.Service, names *namer) []Definition {
 	gqlTypes := []Definition{}
-	addedTypes := make(map[string]usedTypeDesc)
+	addedTypes := make(map[string]bool)
 	var gqlTypeDef Definition
-	var inputDef Definition
+	var inputDefs []Definition
+
+	qualifiedNames := make([]string, 0, len(service.Types))
+	for name := range service.Types {
+		qualifiedNames = append(qualifiedNames, name)
+	}
+	sort.Strings(qualifiedNames)
+
+	for _, name := range qualifiedNames {
+		// Types registered under both their namespace and alias share a name
+		if addedTypes[names.typeName(name)] {
+			continue
+		}
+		addedTypes[names.typeName(name)] = true
 
-	for name, typeDef := range service.Types {
+		typeDef := service.Types[name]
 		switch typeDef.Kind {
-		case "EntityType":
-			gqlTypeDef, inputDef = entityTypeToDefinition(name, service)
-			gqlTypes = append(gqlTypes, inputDef)
-		case "Structure":
-			gqlTypeDef = createDefinition(typeDef.Structure.Name, typeDef.Structure.Properties, service.Types)
-		case "Enum":
-			gqlTypeDef = enumToDefinition(typeDef.Enum)
-		}
-		if addedType, ok := addedTypes[gqlTypeDef.Name]; !ok {
-			addedTypes[gqlTypeDef.Name] = usedTypeDesc{
-				QualifiedName: name,
-				MedSchemaType: typeDef,
-				GqlField:      gqlTypeDef,
+		case mschema.TypeKindEntityType:
+			gqlTypeDef, inputDefs = entityTypeToDefinition(name, service, names)
+			gqlTypes = append(gqlTypes, inputDefs...)
+		case mschema.TypeKindStructure:
+			gqlTypeDef = createDefinition(name, typeDef.Structure, typeDef.Structure.Properties, names)
+			// Complex types are values which the subgraphs of every backend using them may resolve
+			if names.options.Federation {
+				appendDirective(&gqlTypeDef.Element, newShareableDirective())
 			}
-		} else {
-			fmt.Printf("A type was already added for type '%s'. Attempting to add type '%s'. The existing type is '%s'\n", gqlTypeDef.Name, name, addedType.QualifiedName)
-		}
-		// TODO: these are the types which are not actually referenced
-		if name != "Telerik.Sitefinity.Web.Api.OData.Operations.Media.Models.ThumbnailModel" && name != "Telerik.Sitefinity.Folder" {
-			gqlTypes = append(gqlTypes, gqlTypeDef)
+		case mschema.TypeKindEnum:
+			gqlTypeDef = enumToDefinition(name, typeDef.Enum, names)
 		}
+		gqlTypes = append(gqlTypes, gqlTypeDef)
 	}
 
 	return gqlTypes
 }
 
-func Generate(service *mschema.Service) string {
+// Builds the GraphQL schema of the service without printing it, e.g. to compare it with the schema of another version
+func Build(service *mschema.Service, options Options) (*Schema, error) {
+	names, err := newNamer(service, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	originalNameLocations := []string{"OBJECT", "INPUT_OBJECT", "ENUM", "FIELD_DEFINITION", "INPUT_FIELD_DEFINITION", "ARGUMENT_DEFINITION", "ENUM_VALUE"}
+	// Custom scalars keep the names of the type definitions they stand for
+	if options.CustomScalars {
+		originalNameLocations = append(originalNameLocations, "SCALAR")
+	}
+
 	schema := Schema{
 		Query: Definition{
 			Type:    "type",
@@ -301,12 +1033,7 @@ func Generate(service *mschema.Service) string {
 			Fields:  &[]Field{},
 			Element: Element{Name: "Mutation"},
 		},
-		Types: []Definition{
-			{
-				Type:    "type",
-				Element: Element{Name: "System__Void"},
-			},
-		},
+		Types: []Definition{},
 		DirectiveDeclarations: []DirectiveDeclaration{
 			{
 				Applications: []string{"OBJECT", "FIELD_DEFINITION"},
@@ -314,40 +1041,104 @@ func Generate(service *mschema.Service) string {
 			},
 			{
 				Applications: []string{"FIELD_DEFINITION"},
-				Directive: Directive{
-					Name: "connection",
-					Fields: []Field{
-						{
-							Type:    "String",
-							Element: Element{Name: "primaryKey"},
-						},
-						{
-							Type:    "String",
-							Element: Element{Name: "foreignKey"},
-						},
-					},
-				},
+				Directive:    newConnectionDirective("String", "String", "String", "String"),
+			},
+			{
+				Applications: []string{"ARGUMENT_DEFINITION"},
+				Directive:    newExpandDirective("String"),
+			},
+			{
+				Applications: []string{"ARGUMENT_DEFINITION"},
+				Directive:    newUnsupportedPropertiesDirective("String"),
+			},
+			{
+				Applications: []string{"ENUM_VALUE"},
+				Directive:    newEnumValueDirective("String"),
+			},
+			{
+				Applications: originalNameLocations,
+				Directive:    newOriginalNameDirective("String"),
 			},
 		},
 	}
 
-	schema.Types = append(schema.Types, typeDefToDefinition(service)...)
+	if len(service.Links) > 0 {
+		schema.DirectiveDeclarations = append([]DirectiveDeclaration{{
+			Applications: []string{"FIELD_DEFINITION"},
+			Directive:    newJoinDirective("String", "String", "String", "String"),
+		}}, schema.DirectiveDeclarations...)
+	}
+
+	if options.CustomScalars {
+		for _, qualifiedName := range typeDefinitionNames(service) {
+			schema.Types = append(schema.Types, Definition{
+				Type: "scalar",
+				Element: Element{
+					Name:       names.scalarName(qualifiedName),
+					Directives: &[]Directive{newOriginalNameDirective(qualifiedName)},
+				},
+			})
+		}
+	}
+
+	schema.Types = append(schema.Types, typeDefToDefinition(service, names)...)
 
-	// TODO: better way to append or not use pointer?
+	collectionNames := sortedCollectionNames(service.Collections)
+	collectionsPerType := make(map[string]int)
 	for _, collection := range service.Collections {
-		queryFields := createQueryFields(&collection, service)
+		collectionsPerType[collection.EntityType] += 1
+	}
+
+	// Query and Mutation fields share the names, so that a name always refers to the same operation
+	rootNames := make(map[string]string)
+
+	// TODO: better way to append or not use pointer?
+	for _, name := range collectionNames {
+		collection := service.Collections[name]
+		byCollection := collectionsPerType[collection.EntityType] > 1
+
+		product := backendProduct(service, collection.Provenance)
+
+		queryFields := createQueryFields(&collection, byCollection, product, names)
 		queryFuncs := append(*schema.Query.Fields, queryFields...)
 		schema.Query.Fields = &queryFuncs
 
-		mutationFields := createMutationFields(&collection, service)
+		mutationFields := createMutationFields(&collection, byCollection, product, names)
 		mutationFuncs := append(*schema.Mutation.Fields, mutationFields...)
 		schema.Mutation.Fields = &mutationFuncs
+
+		for _, field := range append(queryFields, mutationFields...) {
+			if err := registerRootField(field.Name, fmt.Sprintf("collection '%s'", name), rootNames); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	// TODO: backend doesn't support these
-	// invocations := invocationsToMutations(service.Invocations, service.Types)
-	// asMutationFields := append(*schema.Mutation.Fields, invocations...)
-	// schema.Mutation.Fields = &asMutationFields
+	invocationQueries, invocationMutations, invocationInputs, err := unboundInvocationsToFields(service, names, rootNames)
+	if err != nil {
+		return nil, err
+	}
+	boundQueries, boundMutations, err := boundInvocationsToFields(service, names, rootNames)
+	if err != nil {
+		return nil, err
+	}
+	queryFuncs := append(*schema.Query.Fields, append(invocationQueries, boundQueries...)...)
+	schema.Query.Fields = &queryFuncs
+	mutationFuncs := append(*schema.Mutation.Fields, append(invocationMutations, boundMutations...)...)
+	schema.Mutation.Fields = &mutationFuncs
+	schema.Types = append(schema.Types, invocationInputs...)
 
-	return schema.String()
+	if options.Federation {
+		addFederation(&schema)
+	}
+
+	return &schema, nil
+}
+
+func Generate(service *mschema.Service, options Options) (string, error) {
+	schema, err := Build(service, options)
+	if err != nil {
+		return "", err
+	}
+	return schema.String(), nil
 }
@@ -0,0 +1,297 @@
+package gqlschema
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	mschema "github.com/kinvey/odata-schema/mediation-schema"
+	"github.com/kinvey/odata-schema/utils"
+)
+
+var invalidNameCharsRegexp = regexp.MustCompile(`[^_0-9A-Za-z]`)
+
+// Names which are either built into GraphQL or always declared by the generator
+var reservedTypeNames = []string{"Query", "Mutation", "Subscription", "String", "Int", "Float", "Boolean", "ID"}
+
+type namer struct {
+	options   *Options
+	types     map[string]mschema.Type
+	typeNames map[string]string
+	// Names of the scalars declared for the type definitions by their qualified names
+	scalarNames map[string]string
+	inflector   *utils.Inflector
+}
+
+func (names *namer) warn(format string, args ...interface{}) {
+	if names.options.Warn != nil {
+		names.options.Warn(fmt.Sprintf(format, args...))
+	}
+}
+
+// Makes the name match /[_A-Za-z][_0-9A-Za-z]*/ and keeps it out of the "__" prefix reserved for introspection
+func sanitizeName(name string) string {
+	sanitized := invalidNameCharsRegexp.ReplaceAllString(name, "_")
+	if sanitized == "" || unicode.IsDigit(rune(sanitized[0])) {
+		sanitized = fmt.Sprintf("_%s", sanitized)
+	}
+	if strings.HasPrefix(sanitized, "__") {
+		sanitized = fmt.Sprintf("_%s", strings.TrimLeft(sanitized, "_"))
+	}
+	return sanitized
+}
+
+// Lowers the leading run of upper case letters, keeping the start of the next word, e.g. "IDNumber" -> "idNumber"
+func toCamelCase(name string) string {
+	runes := []rune(name)
+	upperCount := 0
+	for upperCount < len(runes) && unicode.IsUpper(runes[upperCount]) {
+		upperCount += 1
+	}
+	if upperCount > 1 && upperCount < len(runes) {
+		upperCount -= 1
+	}
+	for i := 0; i < upperCount; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+func applyCasing(name string, casing Casing) string {
+	switch casing {
+	case CamelCase:
+		return toCamelCase(name)
+	case PascalCase:
+		return utils.UpperFirstLetter(name)
+	default:
+		return name
+	}
+}
+
+func uniqueName(name string, used map[string]bool) string {
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s_%d", name, i)
+	}
+	used[unique] = true
+	return unique
+}
+
+func getNamespace(qualifiedName string, def mschema.Type) string {
+	return strings.TrimSuffix(strings.TrimSuffix(qualifiedName, getName(def)), ".")
+}
+
+func (n *namer) prefixedTypeName(qualifiedName string) string {
+	def := n.types[qualifiedName]
+	namespace := getNamespace(qualifiedName, def)
+	prefix, found := n.options.NamespacePrefixes[namespace]
+	if !found {
+		prefix = namespace
+		// Types of merged services keep the prefix of their backend rather than the namespace it was added to
+		if def.Provenance != nil && def.Provenance.Name != nil && strings.HasSuffix(qualifiedName, "."+*def.Provenance.Name) {
+			prefix = strings.TrimSuffix(qualifiedName, "."+*def.Provenance.Name)
+		}
+	}
+	return applyCasing(sanitizeName(fmt.Sprintf("%s_%s", prefix, getName(def))), n.options.TypeCasing)
+}
+
+// Names the types after their unqualified names. Types sharing a name get prefixed with their namespace,
+// unless they were renamed explicitly. The names are assigned in the order of the qualified names,
+// so the same service always gets the same names.
+func newNamer(service *mschema.Service, options *Options) (*namer, error) {
+	n := &namer{
+		options:     options,
+		types:       service.Types,
+		typeNames:   make(map[string]string),
+		scalarNames: make(map[string]string),
+		inflector:   utils.NewInflector(options.Plurals),
+	}
+
+	qualifiedNames := make([]string, 0, len(service.Types))
+	for qualifiedName := range service.Types {
+		qualifiedNames = append(qualifiedNames, qualifiedName)
+	}
+	sort.Strings(qualifiedNames)
+
+	usedNames := make(map[string]bool)
+	for _, name := range reservedTypeNames {
+		usedNames[name] = true
+	}
+	if options.Federation {
+		for _, name := range federationTypeNames {
+			usedNames[name] = true
+		}
+	}
+
+	candidates := make(map[string][]string)
+	candidateNames := []string{}
+	for _, qualifiedName := range qualifiedNames {
+		if rename, found := options.TypeRenames[qualifiedName]; found {
+			if usedNames[rename] {
+				return nil, fmt.Errorf("the configured name '%s' of type '%s' is already taken", rename, qualifiedName)
+			}
+			usedNames[rename] = true
+			n.typeNames[qualifiedName] = rename
+			continue
+		}
+
+		candidate := applyCasing(sanitizeName(getName(service.Types[qualifiedName])), options.TypeCasing)
+		if _, found := candidates[candidate]; !found {
+			candidateNames = append(candidateNames, candidate)
+		}
+		candidates[candidate] = append(candidates[candidate], qualifiedName)
+	}
+
+	for _, candidate := range candidateNames {
+		group := candidates[candidate]
+
+		// The same type is registered under both its namespace and its alias
+		isSameType := true
+		for _, qualifiedName := range group[1:] {
+			isSameType = isSameType && reflect.DeepEqual(service.Types[qualifiedName], service.Types[group[0]])
+		}
+
+		if isSameType && !usedNames[candidate] {
+			usedNames[candidate] = true
+			for _, qualifiedName := range group {
+				n.typeNames[qualifiedName] = candidate
+			}
+			continue
+		}
+
+		for _, qualifiedName := range group {
+			n.typeNames[qualifiedName] = uniqueName(n.prefixedTypeName(qualifiedName), usedNames)
+		}
+	}
+
+	if options.CustomScalars {
+		if err := n.nameScalars(typeDefinitionNames(service), usedNames); err != nil {
+			return nil, err
+		}
+	}
+
+	return n, nil
+}
+
+func collectTypeDefinitions(properties map[string]mschema.Property, found map[string]bool) {
+	for _, property := range properties {
+		if property.TypeDefinition != nil {
+			found[*property.TypeDefinition] = true
+		}
+	}
+}
+
+// Qualified names of the type definitions the properties, arguments and results of the service are typed with
+func typeDefinitionNames(service *mschema.Service) []string {
+	found := make(map[string]bool)
+	for _, def := range service.Types {
+		switch {
+		case def.EntityType != nil:
+			collectTypeDefinitions(def.EntityType.Properties, found)
+		case def.Structure != nil:
+			collectTypeDefinitions(def.Structure.Properties, found)
+		}
+	}
+	for _, inv := range service.Invocations {
+		for _, arg := range inv.Arguments {
+			if arg.TypeDefinition != nil {
+				found[*arg.TypeDefinition] = true
+			}
+		}
+		if inv.Result != nil && inv.Result.TypeDefinition != nil {
+			found[*inv.Result.TypeDefinition] = true
+		}
+	}
+
+	qualifiedNames := make([]string, 0, len(found))
+	for qualifiedName := range found {
+		qualifiedNames = append(qualifiedNames, qualifiedName)
+	}
+	sort.Strings(qualifiedNames)
+	return qualifiedNames
+}
+
+// Names the scalars of the type definitions after the types were named, so that they don't change the names of the types.
+// Scalars whose names are taken get prefixed with their namespace.
+func (n *namer) nameScalars(qualifiedNames []string, usedNames map[string]bool) error {
+	for _, qualifiedName := range qualifiedNames {
+		if rename, found := n.options.TypeRenames[qualifiedName]; found {
+			if usedNames[rename] {
+				return fmt.Errorf("the configured name '%s' of type definition '%s' is already taken", rename, qualifiedName)
+			}
+			usedNames[rename] = true
+			n.scalarNames[qualifiedName] = rename
+			continue
+		}
+
+		namespace, name := "", qualifiedName
+		if i := strings.LastIndex(qualifiedName, "."); i >= 0 {
+			namespace, name = qualifiedName[:i], qualifiedName[i+1:]
+		}
+		candidate := applyCasing(sanitizeName(name), n.options.TypeCasing)
+		if usedNames[candidate] {
+			prefix, found := n.options.NamespacePrefixes[namespace]
+			if !found {
+				prefix = namespace
+			}
+			candidate = applyCasing(sanitizeName(fmt.Sprintf("%s_%s", prefix, name)), n.options.TypeCasing)
+		}
+		n.scalarNames[qualifiedName] = uniqueName(candidate, usedNames)
+	}
+	return nil
+}
+
+func (n *namer) typeName(qualifiedName string) string {
+	return n.typeNames[qualifiedName]
+}
+
+func (n *namer) scalarName(qualifiedName string) string {
+	return n.scalarNames[qualifiedName]
+}
+
+func (n *namer) fieldName(name string) string {
+	return applyCasing(sanitizeName(name), n.options.FieldCasing)
+}
+
+func (n *namer) enumValueName(name string) string {
+	sanitized := sanitizeName(name)
+	if sanitized == "true" || sanitized == "false" || sanitized == "null" {
+		sanitized = fmt.Sprintf("%s_", sanitized)
+	}
+	return sanitized
+}
+
+// Names of a single item and a list of items of a collection, based on its entity type.
+// Collections sharing an entity type are named after the collection instead.
+func (n *namer) collectionFieldNames(collection *mschema.Collection, byCollection bool) (string, string) {
+	singleName := n.typeName(collection.EntityType)
+	if byCollection {
+		singleName = utils.UpperFirstLetter(sanitizeName(n.inflector.Singularize(collection.Name)))
+	}
+
+	pluralName := n.inflector.Pluralize(singleName)
+	if pluralName == singleName {
+		pluralName = fmt.Sprintf("%sList", singleName)
+	}
+
+	return singleName, pluralName
+}
+
+func registerRootField(name string, origin string, rootNames map[string]string) error {
+	if existing, taken := rootNames[name]; taken {
+		return fmt.Errorf("root field '%s' of %s collides with the one of %s", name, origin, existing)
+	}
+	rootNames[name] = origin
+	return nil
+}
+
+// Invocations give way to the collection fields, falling back to a name suffixed with their kind, e.g. "usersFunction"
+func registerInvocationField(field *Field, inv mschema.Invocation, origin string, rootNames map[string]string) error {
+	if _, taken := rootNames[field.Name]; taken {
+		field.Name = fmt.Sprintf("%s%s", field.Name, inv.Kind)
+	}
+	return registerRootField(field.Name, origin, rootNames)
+}
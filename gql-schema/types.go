@@ -3,8 +3,6 @@ package gqlschema
 import (
 	"fmt"
 	"strings"
-
-	mschema "github.com/kinvey/odata-schema/mediation-schema"
 )
 
 const indentationSize = 4
@@ -15,12 +13,19 @@ type DirectiveDeclaration struct {
 }
 
 type Schema struct {
+	// Specifications the schema imports definitions from, e.g. Apollo Federation
+	Links                 []SchemaLink
 	Query                 Definition
 	Mutation              Definition
 	Types                 []Definition
 	DirectiveDeclarations []DirectiveDeclaration
 }
 
+type SchemaLink struct {
+	URL     string
+	Imports []string
+}
+
 // A field with no type?
 type Directive struct {
 	Name   string
@@ -28,8 +33,9 @@ type Directive struct {
 }
 
 type Element struct {
-	Name       string
-	Directives *[]Directive
+	Name        string
+	Description *string
+	Directives  *[]Directive
 }
 
 type Field struct {
@@ -45,10 +51,30 @@ type Definition struct {
 	Element
 }
 
-type usedTypeDesc struct {
-	QualifiedName string
-	MedSchemaType mschema.Type
-	GqlField      Definition
+type Casing string
+
+const (
+	PreserveCase Casing = ""
+	CamelCase    Casing = "camel"
+	PascalCase   Casing = "pascal"
+)
+
+type Options struct {
+	// GraphQL names of types by their qualified OData names
+	TypeRenames map[string]string
+	// Prefixes for disambiguating types with the same name by namespace. The namespace itself is used when missing.
+	NamespacePrefixes map[string]string
+	TypeCasing        Casing
+	FieldCasing       Casing
+	// Plurals used for naming the root fields, by singular word or type name
+	Plurals map[string]string
+	// Whether to declare the type definitions as custom scalars named after them rather than using their underlying types
+	CustomScalars bool
+	// Whether to generate an Apollo Federation 2 subgraph, with the entity types of the collections keyed by their keys
+	Federation bool
+	// Called with each element left out of the schema and why, e.g. an invocation whose types aren't defined.
+	// Nothing is reported when unset.
+	Warn func(message string)
 }
 
 func newBackendDirective(product string, collection string, method string, endpoint string) Directive {
@@ -57,10 +83,10 @@ func newBackendDirective(product string, collection string, method string, endpo
 			Type:    product,
 			Element: Element{Name: "product"},
 		},
-		{
-			Type:    collection,
-			Element: Element{Name: "collection"},
-		},
+	}
+
+	if collection != "" {
+		fields = append(fields, Field{Type: collection, Element: Element{Name: "collection"}})
 	}
 
 	if method != "" {
@@ -77,11 +103,173 @@ func newBackendDirective(product string, collection string, method string, endpo
 	}
 }
 
+func newConnectionDirective(navigation string, collection string, primaryKey string, foreignKey string) Directive {
+	fields := []Field{
+		{
+			Type:    navigation,
+			Element: Element{Name: "navigation"},
+		},
+	}
+
+	if collection != "" {
+		fields = append(fields, Field{Type: collection, Element: Element{Name: "collection"}})
+	}
+
+	if primaryKey != "" && foreignKey != "" {
+		fields = append(fields, Field{Type: primaryKey, Element: Element{Name: "primaryKey"}})
+		fields = append(fields, Field{Type: foreignKey, Element: Element{Name: "foreignKey"}})
+	}
+
+	return Directive{
+		Name:   "connection",
+		Fields: fields,
+	}
+}
+
+// Describes how to fetch the targets of a link from the backend of the target collection, joining their target property
+// with the source property of the entity holding the field
+func newJoinDirective(product string, collection string, sourceProperty string, targetProperty string) Directive {
+	return Directive{
+		Name: "join",
+		Fields: []Field{
+			{
+				Type:    product,
+				Element: Element{Name: "product"},
+			},
+			{
+				Type:    collection,
+				Element: Element{Name: "collection"},
+			},
+			{
+				Type:    sourceProperty,
+				Element: Element{Name: "sourceProperty"},
+			},
+			{
+				Type:    targetProperty,
+				Element: Element{Name: "targetProperty"},
+			},
+		},
+	}
+}
+
+func newExpandDirective(option string) Directive {
+	return Directive{
+		Name: "expand",
+		Fields: []Field{
+			{
+				Type:    option,
+				Element: Element{Name: "option"},
+			},
+		},
+	}
+}
+
+// Marks the type as an entity of Apollo Federation which other subgraphs may refer to by the fields
+func newKeyDirective(fields string) Directive {
+	return Directive{
+		Name: "key",
+		Fields: []Field{
+			{
+				Type:    fields,
+				Element: Element{Name: "fields"},
+			},
+		},
+	}
+}
+
+// Lets several subgraphs of Apollo Federation resolve the fields of the type
+func newShareableDirective() Directive {
+	return Directive{Name: "shareable"}
+}
+
+// GraphQL's built-in deprecation, which doesn't need to be declared
+func newDeprecatedDirective(reason string) Directive {
+	if reason == "" {
+		reason = "No longer supported"
+	}
+	return Directive{
+		Name: "deprecated",
+		Fields: []Field{
+			{
+				Type:    reason,
+				Element: Element{Name: "reason"},
+			},
+		},
+	}
+}
+
+// Lists the properties the backend can't apply the argument to
+func newUnsupportedPropertiesDirective(names string) Directive {
+	return Directive{
+		Name: "unsupportedProperties",
+		Fields: []Field{
+			{
+				Type:    names,
+				Element: Element{Name: "names"},
+			},
+		},
+	}
+}
+
+// Records the OData name of an element whose GraphQL name differs from it
+func newOriginalNameDirective(name string) Directive {
+	return Directive{
+		Name: "odata",
+		Fields: []Field{
+			{
+				Type:    name,
+				Element: Element{Name: "name"},
+			},
+		},
+	}
+}
+
+// Records the value of an enum member, so that runtimes can send it to the backend
+func newEnumValueDirective(value string) Directive {
+	return Directive{
+		Name: "enumValue",
+		Fields: []Field{
+			{
+				Type:    value,
+				Element: Element{Name: "value"},
+			},
+		},
+	}
+}
+
+var stringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "", "\t", `\t`)
+
+// Formats the description as a string, or as a block string when it spans multiple lines and may do so
+func formatDescription(description string, multiline bool, indentLevels int) string {
+	indentation := strings.Repeat(" ", indentLevels*indentationSize)
+	if !multiline || !strings.Contains(description, "\n") {
+		return fmt.Sprintf(`%s"%s"`, indentation, stringEscaper.Replace(description))
+	}
+
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, `%s"""`, indentation)
+	for _, line := range strings.Split(strings.ReplaceAll(description, "\r", ""), "\n") {
+		fmt.Fprintf(sb, "\n%s%s", indentation, strings.ReplaceAll(line, `"""`, `\"""`))
+	}
+	fmt.Fprintf(sb, "\n%s\"\"\"", indentation)
+	return sb.String()
+}
+
 func stringifyFields(fields []Field, joiner string, indentLevels int) string {
 	sb := &strings.Builder{}
 	count := len(fields)
+	// Fields on their own lines get their descriptions on the lines above, arguments get them inline
+	multiline := strings.Contains(joiner, "\n")
 
 	for i, field := range fields {
+		if field.Description != nil {
+			sb.WriteString(formatDescription(*field.Description, multiline, indentLevels))
+			if multiline {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString(" ")
+			}
+		}
 		fmt.Fprintf(sb, "%s%s", strings.Repeat(" ", indentLevels*indentationSize), field.String(false, true))
 		if i < count-1 {
 			sb.WriteString(joiner)
@@ -95,13 +283,35 @@ func stringifyFields(fields []Field, joiner string, indentLevels int) string {
 func (def *Definition) String() string {
 	sb := &strings.Builder{}
 
+	if def.Description != nil {
+		sb.WriteString(formatDescription(*def.Description, true, 0))
+		sb.WriteString("\n")
+	}
+
 	fmt.Fprintf(sb, "%s %s ", def.Type, def.Name)
 
 	if def.Directives != nil {
 		for _, dir := range *def.Directives {
-			sb.WriteString(dir.String(false))
+			sb.WriteString(dir.String(true))
+			sb.WriteString(" ")
 		}
-		sb.WriteString(" ")
+	}
+
+	// Unions list their members rather than fields
+	if def.Type == "union" {
+		members := []string{}
+		if def.Fields != nil {
+			for _, member := range *def.Fields {
+				members = append(members, member.Name)
+			}
+		}
+		fmt.Fprintf(sb, "= %s", strings.Join(members, " | "))
+		return sb.String()
+	}
+
+	// Scalars have no body
+	if def.Type == "scalar" {
+		return strings.TrimSuffix(sb.String(), " ")
 	}
 
 	sb.WriteString("{\n")
@@ -118,6 +328,11 @@ func (def *Definition) String() string {
 
 // TODO: quote values :(
 func (directive *Directive) String(quoteValues bool) string {
+	// Directives without arguments go without parentheses, e.g. @shareable
+	if len(directive.Fields) == 0 {
+		return fmt.Sprintf("@%s", directive.Name)
+	}
+
 	sb := &strings.Builder{}
 
 	fmt.Fprintf(sb, "@%s(", directive.Name)
@@ -145,7 +360,7 @@ func (field *Field) String(quoteValue bool, printWhenNoValue bool) string {
 	fieldType := field.Type
 	if fieldType != "" {
 		if quoteValue || strings.Contains(fieldType, "-") || strings.Contains(fieldType, " ") {
-			fieldType = fmt.Sprintf(`"%s"`, fieldType)
+			fieldType = fmt.Sprintf(`"%s"`, stringEscaper.Replace(fieldType))
 		}
 		fieldType = fmt.Sprintf(": %s", fieldType)
 	} else if !printWhenNoValue {
@@ -179,6 +394,14 @@ func (element *Element) String() string {
 	return sb.String()
 }
 
+func (link *SchemaLink) String() string {
+	imports := make([]string, 0, len(link.Imports))
+	for _, name := range link.Imports {
+		imports = append(imports, fmt.Sprintf(`"%s"`, stringEscaper.Replace(name)))
+	}
+	return fmt.Sprintf(`extend schema @link(url: "%s", import: [%s])`, stringEscaper.Replace(link.URL), strings.Join(imports, ", "))
+}
+
 func (dec *DirectiveDeclaration) String() string {
 	sb := &strings.Builder{}
 
@@ -191,6 +414,11 @@ func (dec *DirectiveDeclaration) String() string {
 func (schema *Schema) String() string {
 	sb := &strings.Builder{}
 
+	for _, link := range schema.Links {
+		sb.WriteString(link.String())
+		sb.WriteString("\n\n")
+	}
+
 	for _, declalration := range schema.DirectiveDeclarations {
 		sb.WriteString(declalration.String())
 		sb.WriteString("\n")
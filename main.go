@@ -2,14 +2,21 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	gqlschema "github.com/kinvey/odata-schema/gql-schema"
 	mediationschema "github.com/kinvey/odata-schema/mediation-schema"
 	odataschema "github.com/kinvey/odata-schema/odata-schema"
 )
 
+// Warnings go to stderr, leaving stdout to the results
+func printWarning(message string) {
+	fmt.Fprintln(os.Stderr, message)
+}
+
 func createMediationSchema(backendName string) error {
 	if edm, err := odataschema.Parse(fmt.Sprintf("./schemas/%s.xml", backendName)); err != nil {
 		return err
@@ -23,25 +30,241 @@ func createMediationSchema(backendName string) error {
 	}
 }
 
-func generateMediationGqlSchema(backendName string) string {
-	bytes, _ := os.ReadFile(fmt.Sprintf("./schemas/%s-mediation-schema.json", backendName))
-	service := &mediationschema.Service{}
-
-	json.Unmarshal(bytes, service)
+func generateMediationGqlSchema(backendName string) (string, error) {
+	bytes, err := os.ReadFile(fmt.Sprintf("./schemas/%s-mediation-schema.json", backendName))
+	if err != nil {
+		return "", err
+	}
+	service, err := mediationschema.Load(bytes)
+	if err != nil {
+		return "", err
+	}
+	// Documents of older versions don't name their backends
+	if service.Backend == "" {
+		service.Backend = backendName
+	}
+	if problems := mediationschema.Validate(service); len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		return "", fmt.Errorf("the mediation schema of '%s' has %d integrity errors", backendName, len(problems))
+	}
 
-	schema := gqlschema.Generate(service)
+	schema, err := gqlschema.Generate(service, gqlschema.Options{Warn: printWarning})
+	if err != nil {
+		return "", err
+	}
 
 	os.WriteFile(fmt.Sprintf("./schemas/%s.gql", backendName), []byte(schema), 0644)
 
-	return schema
+	return schema, nil
+}
+
+// Prints the diagnostics of the metadata, telling whether it's free of errors
+func validateSchema(filePath string) (bool, error) {
+	edm, err := odataschema.Parse(filePath)
+	if err != nil {
+		return false, err
+	}
+
+	diagnostics := odataschema.Validate(edm)
+	for _, diagnostic := range diagnostics {
+		fmt.Println(diagnostic)
+	}
+
+	return !odataschema.HasErrors(diagnostics), nil
+}
+
+func loadMediationSchema(filePath string) (*mediationschema.Service, error) {
+	bytes, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return mediationschema.Load(bytes)
+}
+
+// Prefixes of the backends given as backend=prefix
+type prefixFlags map[string]string
+
+func (prefixes prefixFlags) String() string {
+	return fmt.Sprint(map[string]string(prefixes))
+}
+
+func (prefixes prefixFlags) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("'%s' is not of the form backend=prefix", value)
+	}
+	prefixes[parts[0]] = parts[1]
+	return nil
+}
+
+// Merges the metadata of the backends into one mediation schema and writes it along with its GraphQL schema
+func mergeBackends(args []string) error {
+	prefixes := prefixFlags{}
+	flags := flag.NewFlagSet("merge", flag.ContinueOnError)
+	name := flags.String("name", "merged", "name of the merged service and of the files written")
+	linksPath := flags.String("links", "", "JSON file listing the links between the collections of the backends")
+	federation := flags.Bool("federation", false, "generate an Apollo Federation 2 subgraph")
+	flags.Var(prefixes, "prefix", "prefix of the names of a backend as backend=prefix, may be repeated")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 2 {
+		return fmt.Errorf("usage: merge [-name name] [-prefix backend=prefix]... [-links file] [-federation] <backend> <backend>...")
+	}
+
+	services := []*mediationschema.Service{}
+	for _, backendName := range flags.Args() {
+		edm, err := odataschema.Parse(fmt.Sprintf("./schemas/%s.xml", backendName))
+		if err != nil {
+			return err
+		}
+		service, err := mediationschema.Parse(backendName, edm)
+		if err != nil {
+			return err
+		}
+		services = append(services, service)
+	}
+
+	merged, err := mediationschema.Merge(services, mediationschema.MergeOptions{Name: *name, Prefixes: prefixes})
+	if err != nil {
+		return err
+	}
+	if *linksPath != "" {
+		bytes, err := os.ReadFile(*linksPath)
+		if err != nil {
+			return err
+		}
+		links := []mediationschema.Link{}
+		if err := json.Unmarshal(bytes, &links); err != nil {
+			return fmt.Errorf("%s: %w", *linksPath, err)
+		}
+		merged.Links = append(merged.Links, links...)
+	}
+	if problems := mediationschema.Validate(merged); len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		return fmt.Errorf("the merged mediation schema has %d integrity errors", len(problems))
+	}
+
+	bytes, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(fmt.Sprintf("./schemas/%s-mediation-schema.json", *name), bytes, 0644); err != nil {
+		return err
+	}
+
+	schema, err := gqlschema.Generate(merged, gqlschema.Options{Federation: *federation, Warn: printWarning})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fmt.Sprintf("./schemas/%s.gql", *name), []byte(schema), 0644)
+}
+
+// Prints the changes between two mediation schemas, or between the GraphQL schemas generated from them,
+// telling whether they are free of breaking changes
+func diffSchemas(args []string) (bool, error) {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	format := flags.String("format", string(mediationschema.ChangesText), "output format: text, json or markdown")
+	graphql := flags.Bool("graphql", false, "compare the generated GraphQL schemas")
+	if err := flags.Parse(args); err != nil {
+		return false, err
+	}
+	if flags.NArg() != 2 {
+		return false, fmt.Errorf("usage: diff [-format text|json|markdown] [-graphql] <old> <new>")
+	}
+
+	old, err := loadMediationSchema(flags.Arg(0))
+	if err != nil {
+		return false, err
+	}
+	new, err := loadMediationSchema(flags.Arg(1))
+	if err != nil {
+		return false, err
+	}
+
+	var changes []mediationschema.Change
+	if *graphql {
+		oldSchema, err := gqlschema.Build(old, gqlschema.Options{Warn: printWarning})
+		if err != nil {
+			return false, err
+		}
+		newSchema, err := gqlschema.Build(new, gqlschema.Options{Warn: printWarning})
+		if err != nil {
+			return false, err
+		}
+		changes = gqlschema.Diff(oldSchema, newSchema)
+	} else {
+		changes = mediationschema.Diff(old, new)
+	}
+
+	if err := mediationschema.WriteChanges(os.Stdout, changes, mediationschema.ChangesFormat(*format)); err != nil {
+		return false, err
+	}
+	return !mediationschema.HasBreakingChanges(changes), nil
 }
 
 func main() {
+	// validate <file>...
+	if len(os.Args) > 2 && os.Args[1] == "validate" {
+		valid := true
+		for _, filePath := range os.Args[2:] {
+			if ok, err := validateSchema(filePath); err != nil {
+				fmt.Println(err)
+				valid = false
+			} else {
+				valid = valid && ok
+			}
+		}
+		if !valid {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// diff [-format text|json|markdown] [-graphql] <old> <new>: exits with 1 when there are breaking changes
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		if ok, err := diffSchemas(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		} else if !ok {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// merge [-name name] [-prefix backend=prefix]... [-links file] [-federation] <backend> <backend>...
+	if len(os.Args) > 1 && os.Args[1] == "merge" {
+		if err := mergeBackends(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// jsonschema: writes the JSON Schema of the mediation schema documents
+	if len(os.Args) > 1 && os.Args[1] == "jsonschema" {
+		schema, err := mediationschema.JSONSchema()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile("./schemas/mediation-schema.schema.json", append(schema, '\n'), 0644); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	schemaName := "sitefinity"
 	if err := createMediationSchema(schemaName); err != nil {
 		fmt.Print(err)
+	} else if gqlSchema, err := generateMediationGqlSchema(schemaName); err != nil {
+		fmt.Print(err)
 	} else {
-		gqlSchema := generateMediationGqlSchema(schemaName)
 		fmt.Println(len(gqlSchema))
 	}
 }
@@ -0,0 +1,220 @@
+package mediationschema
+
+import (
+	"fmt"
+	"strings"
+
+	ods "github.com/kinvey/odata-schema/odata-schema"
+	"github.com/kinvey/odata-schema/utils"
+)
+
+const (
+	coreNamespace         = "Org.OData.Core.V1"
+	capabilitiesNamespace = "Org.OData.Capabilities.V1"
+)
+
+// Replaces the alias of a qualified name, e.g. "Capabilities.Insertable", with the namespace it stands for
+func (objects *edmObjects) normalizeQualifiedName(name string) string {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return name
+	}
+	if namespace, ok := objects.aliases[name[:i]]; ok {
+		return namespace + name[i:]
+	}
+	return name
+}
+
+// Normalizes the qualified name the target path starts with, e.g. "Alias.Container/Set" -> "Namespace.Container/Set"
+func (objects *edmObjects) normalizeTarget(target string) string {
+	segments := strings.SplitN(target, "/", 2)
+	segments[0] = objects.normalizeQualifiedName(segments[0])
+	return strings.Join(segments, "/")
+}
+
+// Finds the unqualified annotation of the term, looking at the inline annotations first
+// and at the ones applied to the target from outside next
+func (objects *edmObjects) findAnnotation(inline []ods.Annotation, target string, term string) *ods.Annotation {
+	for i := range inline {
+		if inline[i].Qualifier == nil && objects.normalizeQualifiedName(inline[i].Term) == term {
+			return &inline[i]
+		}
+	}
+
+	external := objects.annotations[target]
+	for i := range external {
+		if external[i].Qualifier == nil && objects.normalizeQualifiedName(external[i].Term) == term {
+			return &external[i]
+		}
+	}
+
+	return nil
+}
+
+func recordBool(record *ods.Record, property string, defaultValue bool) bool {
+	if value := record.PropertyValue(property); value != nil {
+		if b, ok := value.BoolValue(); ok {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+func recordValues(record *ods.Record, property string) []string {
+	if value := record.PropertyValue(property); value != nil && value.Collection != nil {
+		return value.Collection.Values()
+	}
+	return nil
+}
+
+// Reads the restrictions of the Capabilities vocabulary annotated on the entity set. Everything is supported unless restricted.
+func mapCapabilities(entitySet ods.EntitySet, objects *edmObjects) Capabilities {
+	capabilities := Capabilities{
+		Insertable: true,
+		Updatable:  true,
+		Deletable:  true,
+		Filterable: true,
+		Sortable:   true,
+		Expandable: true,
+		Countable:  true,
+	}
+
+	target := objects.containerTarget(entitySet.Name)
+	findRecord := func(term string) *ods.Record {
+		if annotation := objects.findAnnotation(entitySet.Annotations, target, term); annotation != nil {
+			return annotation.Record
+		}
+		return nil
+	}
+
+	if record := findRecord(capabilitiesNamespace + ".InsertRestrictions"); record != nil {
+		capabilities.Insertable = recordBool(record, "Insertable", true)
+	}
+	if record := findRecord(capabilitiesNamespace + ".UpdateRestrictions"); record != nil {
+		capabilities.Updatable = recordBool(record, "Updatable", true)
+	}
+	if record := findRecord(capabilitiesNamespace + ".DeleteRestrictions"); record != nil {
+		capabilities.Deletable = recordBool(record, "Deletable", true)
+	}
+	if record := findRecord(capabilitiesNamespace + ".FilterRestrictions"); record != nil {
+		capabilities.Filterable = recordBool(record, "Filterable", true)
+		capabilities.NonFilterableProperties = recordValues(record, "NonFilterableProperties")
+	}
+	if record := findRecord(capabilitiesNamespace + ".SortRestrictions"); record != nil {
+		capabilities.Sortable = recordBool(record, "Sortable", true)
+		capabilities.NonSortableProperties = recordValues(record, "NonSortableProperties")
+	}
+	if record := findRecord(capabilitiesNamespace + ".ExpandRestrictions"); record != nil {
+		capabilities.Expandable = recordBool(record, "Expandable", true)
+		capabilities.NonExpandableProperties = recordValues(record, "NonExpandableProperties")
+	}
+	if record := findRecord(capabilitiesNamespace + ".CountRestrictions"); record != nil {
+		capabilities.Countable = recordBool(record, "Countable", true)
+	}
+
+	// Sitefinity lists the properties it can't filter and sort by as collections of Core terms
+	if annotation := objects.findAnnotation(entitySet.Annotations, target, coreNamespace+".FilterRestrictions"); annotation != nil && annotation.Collection != nil {
+		capabilities.NonFilterableProperties = append(capabilities.NonFilterableProperties, annotation.Collection.Values()...)
+	}
+	if annotation := objects.findAnnotation(entitySet.Annotations, target, coreNamespace+".SortRestrictions"); annotation != nil && annotation.Collection != nil {
+		capabilities.NonSortableProperties = append(capabilities.NonSortableProperties, annotation.Collection.Values()...)
+	}
+
+	return capabilities
+}
+
+// Combines the Core descriptions of an element, or its documentation in CSDL v2/v3, into a single description
+func mapDescription(inline []ods.Annotation, documentation *ods.Documentation, target string, objects *edmObjects) *string {
+	parts := []string{}
+	for _, term := range []string{"Description", "LongDescription"} {
+		if annotation := objects.findAnnotation(inline, target, fmt.Sprintf("%s.%s", coreNamespace, term)); annotation != nil {
+			if value, ok := annotation.StringValue(); ok && strings.TrimSpace(value) != "" {
+				parts = append(parts, strings.TrimSpace(value))
+			}
+		}
+	}
+
+	if len(parts) == 0 && documentation != nil {
+		for _, value := range []*string{documentation.Summary, documentation.LongDescription} {
+			if value != nil && strings.TrimSpace(*value) != "" {
+				parts = append(parts, strings.TrimSpace(*value))
+			}
+		}
+	}
+
+	if len(parts) == 0 {
+		return nil
+	}
+	description := strings.Join(parts, "\n\n")
+	return &description
+}
+
+// Tag terms like Core.Computed apply unless their value is explicitly false
+func (objects *edmObjects) hasTag(inline []ods.Annotation, target string, term string) bool {
+	annotation := objects.findAnnotation(inline, target, term)
+	if annotation == nil {
+		return false
+	}
+	if value, ok := annotation.BoolValue(); ok {
+		return value
+	}
+	return true
+}
+
+// Whether Core.Permissions, e.g. "Org.OData.Core.V1.Permission/Read", leaves out writing the property
+func (objects *edmObjects) isReadOnly(inline []ods.Annotation, target string) bool {
+	annotation := objects.findAnnotation(inline, target, coreNamespace+".Permissions")
+	if annotation == nil {
+		return false
+	}
+	value, ok := annotation.EnumMemberValue()
+	if !ok {
+		return false
+	}
+
+	for _, permission := range enumMemberNames(value) {
+		if permission == "Write" || permission == "ReadWrite" {
+			return false
+		}
+	}
+	return true
+}
+
+// The names of the members of an enum value, which CSDL XML qualifies with the enum type, e.g.
+// "Core.Permission/Read Core.Permission/Write", and CSDL JSON may leave bare, e.g. "Read,Write"
+func enumMemberNames(value string) []string {
+	members := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+	for i, member := range members {
+		members[i] = member[strings.LastIndex(member, "/")+1:]
+	}
+	return members
+}
+
+// The reason of the deprecation among the Core.Revisions of an element, when it has been deprecated
+func (objects *edmObjects) mapDeprecation(inline []ods.Annotation, target string) *string {
+	annotation := objects.findAnnotation(inline, target, coreNamespace+".Revisions")
+	if annotation == nil || annotation.Collection == nil {
+		return nil
+	}
+
+	for i := range annotation.Collection.Records {
+		revision := &annotation.Collection.Records[i]
+		kind := revision.PropertyValue("Kind")
+		if kind == nil {
+			continue
+		}
+		if value, ok := kind.EnumMemberValue(); !ok || !utils.SliceContainsString(enumMemberNames(value), "Deprecated") {
+			continue
+		}
+
+		reason := ""
+		if description := revision.PropertyValue("Description"); description != nil {
+			reason, _ = description.StringValue()
+		}
+		return &reason
+	}
+
+	return nil
+}
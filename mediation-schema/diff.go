@@ -0,0 +1,457 @@
+package mediationschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// How a change affects the clients of a service
+type ChangeLevel string
+
+const (
+	// Clients relying on what changed stop working, e.g. a removed property
+	ChangeBreaking ChangeLevel = "breaking"
+	// Clients may behave differently, e.g. when they receive a new enum member they don't know
+	ChangeDangerous ChangeLevel = "dangerous"
+	ChangeSafe      ChangeLevel = "safe"
+)
+
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+type Change struct {
+	Level ChangeLevel
+	Kind  ChangeKind
+	// JSON path of what changed, e.g. `$.Types["Trippin.Person"].Properties["Emails"]`
+	Path    string
+	Message string
+}
+
+func (change Change) String() string {
+	return fmt.Sprintf("%s: %s: %s", change.Level, change.Path, change.Message)
+}
+
+func HasBreakingChanges(changes []Change) bool {
+	for _, change := range changes {
+		if change.Level == ChangeBreaking {
+			return true
+		}
+	}
+	return false
+}
+
+type differ struct {
+	changes []Change
+	// Backends of the services, which their elements without provenance come from
+	oldBackend string
+	newBackend string
+}
+
+func (d *differ) report(level ChangeLevel, kind ChangeKind, path string, format string, args ...interface{}) {
+	d.changes = append(d.changes, Change{Level: level, Kind: kind, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Reports a flag which restricts clients when it's set, e.g. ReadOnly
+func (d *differ) restriction(path string, name string, was bool, is bool) {
+	if was == is {
+		return
+	}
+	if is {
+		d.report(ChangeBreaking, ChangeChanged, path, "became %s", name)
+	} else {
+		d.report(ChangeSafe, ChangeChanged, path, "is no longer %s", name)
+	}
+}
+
+// Reports a flag which allows clients more when it's set, e.g. Insertable
+func (d *differ) permission(path string, name string, was bool, is bool) {
+	d.restriction(path, "not "+name, !was, !is)
+}
+
+func (d *differ) deprecation(path string, was *string, is *string) {
+	if was == nil && is != nil {
+		d.report(ChangeDangerous, ChangeChanged, path, "became deprecated: %s", *is)
+	} else if was != nil && is == nil {
+		d.report(ChangeSafe, ChangeChanged, path, "is no longer deprecated")
+	}
+}
+
+func provenanceBackend(provenance *Provenance, serviceBackend string) string {
+	if provenance == nil {
+		return serviceBackend
+	}
+	return provenance.Backend
+}
+
+// Clients are served the same way by another backend, but only as long as it behaves the same
+func (d *differ) backend(path string, old *Provenance, new *Provenance) {
+	oldBackend, newBackend := provenanceBackend(old, d.oldBackend), provenanceBackend(new, d.newBackend)
+	if oldBackend != newBackend {
+		d.report(ChangeDangerous, ChangeChanged, path, "backend changed from '%s' to '%s'", oldBackend, newBackend)
+	}
+}
+
+func describeProperty(property Property) string {
+	description := fmt.Sprintf("%s %s", property.Kind, property.Type)
+	if property.IsCollection {
+		description = fmt.Sprintf("collection of %s", description)
+	}
+	return description
+}
+
+// Compares the properties, which are the fields clients both read and write.
+// Arguments are only written, so clients aren't affected by them becoming optional.
+func (d *differ) property(path string, old Property, new Property, isArgument bool) {
+	if old.Kind != new.Kind || old.Type != new.Type || old.IsCollection != new.IsCollection {
+		d.report(ChangeBreaking, ChangeChanged, path, "type changed from %s to %s", describeProperty(old), describeProperty(new))
+	}
+	if old.Required != new.Required {
+		switch {
+		case new.Required:
+			d.report(ChangeBreaking, ChangeChanged, path, "became required")
+		case isArgument:
+			d.report(ChangeSafe, ChangeChanged, path, "became optional")
+		default:
+			d.report(ChangeDangerous, ChangeChanged, path, "became nullable")
+		}
+	}
+	d.restriction(path, "read-only", old.ReadOnly, new.ReadOnly)
+	d.restriction(path, "computed", old.Computed, new.Computed)
+	d.restriction(path, "immutable", old.Immutable, new.Immutable)
+	if stringValue(old.RelationCollection) != stringValue(new.RelationCollection) {
+		d.report(ChangeDangerous, ChangeChanged, path, "relation collection changed from '%s' to '%s'", stringValue(old.RelationCollection), stringValue(new.RelationCollection))
+	}
+	d.deprecation(path, old.Deprecation, new.Deprecation)
+}
+
+func (d *differ) properties(path string, old map[string]Property, new map[string]Property) {
+	for _, name := range sortedKeys(old) {
+		propertyPath := memberPath(path, name)
+		if property, found := new[name]; found {
+			d.property(propertyPath, old[name], property, false)
+		} else {
+			d.report(ChangeBreaking, ChangeRemoved, propertyPath, "property was removed")
+		}
+	}
+	for _, name := range sortedKeys(new) {
+		if _, found := old[name]; found {
+			continue
+		}
+		if new[name].Required && !new[name].ReadOnly && !new[name].Computed {
+			d.report(ChangeBreaking, ChangeAdded, memberPath(path, name), "required property was added")
+		} else {
+			d.report(ChangeSafe, ChangeAdded, memberPath(path, name), "property was added")
+		}
+	}
+}
+
+func (d *differ) collection(path string, old Collection, new Collection) {
+	if old.EntityType != new.EntityType {
+		d.report(ChangeBreaking, ChangeChanged, path+".EntityType", "entity type changed from '%s' to '%s'", old.EntityType, new.EntityType)
+	}
+	if old.Streamable != new.Streamable {
+		d.report(ChangeDangerous, ChangeChanged, path+".Streamable", "streamable changed from %t to %t", old.Streamable, new.Streamable)
+	}
+	d.deprecation(path, old.Deprecation, new.Deprecation)
+	d.backend(path, old.Provenance, new.Provenance)
+
+	// Collections without capabilities support everything
+	oldCapabilities, newCapabilities := allCapabilities, allCapabilities
+	if old.Capabilities != nil {
+		oldCapabilities = *old.Capabilities
+	}
+	if new.Capabilities != nil {
+		newCapabilities = *new.Capabilities
+	}
+	capabilitiesPath := path + ".Capabilities"
+	d.permission(capabilitiesPath, "insertable", oldCapabilities.Insertable, newCapabilities.Insertable)
+	d.permission(capabilitiesPath, "updatable", oldCapabilities.Updatable, newCapabilities.Updatable)
+	d.permission(capabilitiesPath, "deletable", oldCapabilities.Deletable, newCapabilities.Deletable)
+	d.permission(capabilitiesPath, "filterable", oldCapabilities.Filterable, newCapabilities.Filterable)
+	d.permission(capabilitiesPath, "sortable", oldCapabilities.Sortable, newCapabilities.Sortable)
+	d.permission(capabilitiesPath, "expandable", oldCapabilities.Expandable, newCapabilities.Expandable)
+	d.permission(capabilitiesPath, "countable", oldCapabilities.Countable, newCapabilities.Countable)
+}
+
+var allCapabilities = Capabilities{
+	Insertable: true,
+	Updatable:  true,
+	Deletable:  true,
+	Filterable: true,
+	Sortable:   true,
+	Expandable: true,
+	Countable:  true,
+}
+
+func (d *differ) enum(path string, old *Enum, new *Enum) {
+	if old.ValuesType != new.ValuesType {
+		d.report(ChangeBreaking, ChangeChanged, path+".ValuesType", "values type changed from '%s' to '%s'", old.ValuesType, new.ValuesType)
+	}
+	if old.Multiselect != new.Multiselect {
+		d.report(ChangeBreaking, ChangeChanged, path+".Multiselect", "multiselect changed from %t to %t", old.Multiselect, new.Multiselect)
+	}
+
+	newMembers := make(map[string]EnumMember)
+	for _, member := range new.Members {
+		newMembers[member.Name] = member
+	}
+	oldMembers := make(map[string]bool)
+	for _, member := range old.Members {
+		oldMembers[member.Name] = true
+		memberPath := memberPath(path+".Members", member.Name)
+		if newMember, found := newMembers[member.Name]; !found {
+			d.report(ChangeBreaking, ChangeRemoved, memberPath, "member was removed")
+		} else {
+			if newMember.Value != member.Value {
+				d.report(ChangeBreaking, ChangeChanged, memberPath, "value changed from %d to %d", member.Value, newMember.Value)
+			}
+			d.deprecation(memberPath, member.Deprecation, newMember.Deprecation)
+		}
+	}
+	for _, member := range new.Members {
+		if !oldMembers[member.Name] {
+			d.report(ChangeDangerous, ChangeAdded, memberPath(path+".Members", member.Name), "member was added")
+		}
+	}
+}
+
+func (d *differ) typeDefinition(path string, old Type, new Type) {
+	if old.Kind != new.Kind {
+		d.report(ChangeBreaking, ChangeChanged, path, "kind changed from '%s' to '%s'", old.Kind, new.Kind)
+		return
+	}
+
+	switch {
+	case old.EntityType != nil && new.EntityType != nil:
+		if strings.Join(old.EntityType.Key, ",") != strings.Join(new.EntityType.Key, ",") {
+			d.report(ChangeBreaking, ChangeChanged, path+".Key", "key changed from (%s) to (%s)", strings.Join(old.EntityType.Key, ", "), strings.Join(new.EntityType.Key, ", "))
+		}
+		if stringValue(old.EntityType.BaseType) != stringValue(new.EntityType.BaseType) {
+			d.report(ChangeDangerous, ChangeChanged, path+".BaseType", "base type changed from '%s' to '%s'", stringValue(old.EntityType.BaseType), stringValue(new.EntityType.BaseType))
+		}
+		d.properties(path+".Properties", old.EntityType.Properties, new.EntityType.Properties)
+	case old.Structure != nil && new.Structure != nil:
+		d.properties(path+".Properties", old.Structure.Properties, new.Structure.Properties)
+	case old.Enum != nil && new.Enum != nil:
+		d.enum(path, old.Enum, new.Enum)
+	}
+}
+
+func (d *differ) invocation(path string, old Invocation, new Invocation) {
+	if old.Kind != new.Kind {
+		d.report(ChangeBreaking, ChangeChanged, path+".Kind", "kind changed from '%s' to '%s'", old.Kind, new.Kind)
+	}
+	if old.BindingType != new.BindingType || stringValue(old.BoundTo) != stringValue(new.BoundTo) {
+		d.report(ChangeBreaking, ChangeChanged, path+".BindingType", "binding changed from %s '%s' to %s '%s'", old.BindingType, stringValue(old.BoundTo), new.BindingType, stringValue(new.BoundTo))
+	}
+	d.deprecation(path, old.Deprecation, new.Deprecation)
+	d.backend(path, old.Provenance, new.Provenance)
+
+	newArguments := make(map[string]InvocationArgument)
+	for _, argument := range new.Arguments {
+		newArguments[argument.Name] = argument
+	}
+	oldArguments := make(map[string]bool)
+	for _, argument := range old.Arguments {
+		oldArguments[argument.Name] = true
+		argumentPath := memberPath(path+".Arguments", argument.Name)
+		if newArgument, found := newArguments[argument.Name]; found {
+			d.property(argumentPath, argument.Property, newArgument.Property, true)
+		} else {
+			d.report(ChangeBreaking, ChangeRemoved, argumentPath, "argument was removed")
+		}
+	}
+	for _, argument := range new.Arguments {
+		if oldArguments[argument.Name] {
+			continue
+		}
+		if argument.Required {
+			d.report(ChangeBreaking, ChangeAdded, memberPath(path+".Arguments", argument.Name), "required argument was added")
+		} else {
+			d.report(ChangeSafe, ChangeAdded, memberPath(path+".Arguments", argument.Name), "optional argument was added")
+		}
+	}
+
+	switch {
+	case old.Result != nil && new.Result != nil:
+		d.property(path+".Result", *old.Result, *new.Result, false)
+	case old.Result != nil:
+		d.report(ChangeBreaking, ChangeRemoved, path+".Result", "result was removed")
+	case new.Result != nil:
+		d.report(ChangeSafe, ChangeAdded, path+".Result", "result was added")
+	}
+	if stringValue(old.ResultCollection) != stringValue(new.ResultCollection) {
+		d.report(ChangeDangerous, ChangeChanged, path+".ResultCollection", "result collection changed from '%s' to '%s'", stringValue(old.ResultCollection), stringValue(new.ResultCollection))
+	}
+}
+
+func linkKey(link Link) string {
+	return fmt.Sprintf("%s/%s", link.SourceCollection, link.Name)
+}
+
+// Links are told apart by their source collections and names, as they're listed rather than named
+func (d *differ) links(old []Link, new []Link) {
+	newLinks := make(map[string]int)
+	for i, link := range new {
+		newLinks[linkKey(link)] = i
+	}
+	oldLinks := make(map[string]bool)
+	for i, link := range old {
+		oldLinks[linkKey(link)] = true
+		j, found := newLinks[linkKey(link)]
+		if !found {
+			d.report(ChangeBreaking, ChangeRemoved, fmt.Sprintf("$.Links[%d]", i), "link '%s' of collection '%s' was removed", link.Name, link.SourceCollection)
+			continue
+		}
+		path := fmt.Sprintf("$.Links[%d]", j)
+		if newLink := new[j]; newLink.SourceProperty != link.SourceProperty || newLink.TargetCollection != link.TargetCollection || newLink.TargetProperty != link.TargetProperty {
+			d.report(ChangeBreaking, ChangeChanged, path, "join changed from %s = %s.%s to %s = %s.%s",
+				link.SourceProperty, link.TargetCollection, link.TargetProperty, newLink.SourceProperty, newLink.TargetCollection, newLink.TargetProperty)
+		} else if newLink.IsCollection != link.IsCollection {
+			d.report(ChangeBreaking, ChangeChanged, path, "collection changed from %t to %t", link.IsCollection, newLink.IsCollection)
+		}
+	}
+	for i, link := range new {
+		if !oldLinks[linkKey(link)] {
+			d.report(ChangeSafe, ChangeAdded, fmt.Sprintf("$.Links[%d]", i), "link '%s' of collection '%s' was added", link.Name, link.SourceCollection)
+		}
+	}
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// Compares two versions of a service, classifying every difference by how it affects the clients of the old version.
+// Changes of descriptions aren't reported.
+func Diff(old *Service, new *Service) []Change {
+	d := &differ{changes: []Change{}, oldBackend: old.Backend, newBackend: new.Backend}
+
+	for _, name := range sortedKeys(old.Collections) {
+		path := memberPath("$.Collections", name)
+		if collection, found := new.Collections[name]; found {
+			d.collection(path, old.Collections[name], collection)
+		} else {
+			d.report(ChangeBreaking, ChangeRemoved, path, "collection was removed")
+		}
+	}
+	for _, name := range sortedKeys(new.Collections) {
+		if _, found := old.Collections[name]; !found {
+			d.report(ChangeSafe, ChangeAdded, memberPath("$.Collections", name), "collection was added")
+		}
+	}
+
+	for _, name := range sortedKeys(old.Types) {
+		path := memberPath("$.Types", name)
+		if def, found := new.Types[name]; found {
+			d.typeDefinition(path, old.Types[name], def)
+		} else {
+			d.report(ChangeBreaking, ChangeRemoved, path, "type was removed")
+		}
+	}
+	for _, name := range sortedKeys(new.Types) {
+		if _, found := old.Types[name]; !found {
+			d.report(ChangeSafe, ChangeAdded, memberPath("$.Types", name), "type was added")
+		}
+	}
+
+	for _, name := range sortedKeys(old.Invocations) {
+		path := memberPath("$.Invocations", name)
+		if inv, found := new.Invocations[name]; found {
+			d.invocation(path, old.Invocations[name], inv)
+		} else {
+			d.report(ChangeBreaking, ChangeRemoved, path, "invocation was removed")
+		}
+	}
+	for _, name := range sortedKeys(new.Invocations) {
+		if _, found := old.Invocations[name]; !found {
+			d.report(ChangeSafe, ChangeAdded, memberPath("$.Invocations", name), "invocation was added")
+		}
+	}
+
+	d.links(old.Links, new.Links)
+
+	return d.changes
+}
+
+type ChangesFormat string
+
+const (
+	ChangesText     ChangesFormat = "text"
+	ChangesJSON     ChangesFormat = "json"
+	ChangesMarkdown ChangesFormat = "markdown"
+)
+
+var changeLevels = []ChangeLevel{ChangeBreaking, ChangeDangerous, ChangeSafe}
+
+// Groups the changes by level, keeping their order within each level
+func changesByLevel(changes []Change) map[ChangeLevel][]Change {
+	grouped := make(map[ChangeLevel][]Change)
+	for _, change := range changes {
+		grouped[change.Level] = append(grouped[change.Level], change)
+	}
+	for _, level := range changeLevels {
+		sort.SliceStable(grouped[level], func(a, b int) bool {
+			return grouped[level][a].Path < grouped[level][b].Path
+		})
+	}
+	return grouped
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\n", " ")
+
+// Writes the changes for people, one per line or as Markdown tables for release notes, or as JSON for tools
+func WriteChanges(w io.Writer, changes []Change, format ChangesFormat) error {
+	switch format {
+	case ChangesJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(changes)
+	case ChangesText:
+		grouped := changesByLevel(changes)
+		for _, level := range changeLevels {
+			for _, change := range grouped[level] {
+				if _, err := fmt.Fprintln(w, change); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case ChangesMarkdown:
+		grouped := changesByLevel(changes)
+		if len(changes) == 0 {
+			_, err := fmt.Fprintln(w, "No changes.")
+			return err
+		}
+		for _, level := range changeLevels {
+			if len(grouped[level]) == 0 {
+				continue
+			}
+			title := strings.Title(string(level))
+			if _, err := fmt.Fprintf(w, "## %s changes\n\n| Change | Path | Description |\n| --- | --- | --- |\n", title); err != nil {
+				return err
+			}
+			for _, change := range grouped[level] {
+				if _, err := fmt.Fprintf(w, "| %s | `%s` | %s |\n", change.Kind, change.Path, markdownEscaper.Replace(change.Message)); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown format '%s'", format)
+}
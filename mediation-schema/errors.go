@@ -2,6 +2,8 @@ package mediationschema
 
 import (
 	"fmt"
+
+	ods "github.com/kinvey/odata-schema/odata-schema"
 )
 
 type MediationSchemaError struct {
@@ -35,3 +37,13 @@ func NewMediationSchemaError(msg, description string) MediationSchemaError {
 }
 
 var ErrDuplicateDefinition MediationSchemaError = NewMediationSchemaError("duplicate definition", "the object was defined more than once")
+var ErrInvalidMetadata MediationSchemaError = NewMediationSchemaError("invalid metadata", "the metadata can't be mapped")
+var ErrUnsupportedType MediationSchemaError = NewMediationSchemaError("unsupported type", "the primitive type can't be mapped")
+
+// Prefixes the error with the position of the element it's about, e.g. "sitefinity.xml:10234:9: ...", when the element has one
+func errorAt(position ods.Position, err error) error {
+	if err == nil || !position.IsValid() {
+		return err
+	}
+	return fmt.Errorf("%s: %w", position, err)
+}
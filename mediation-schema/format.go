@@ -0,0 +1,295 @@
+package mediationschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Version of the format of the documents the services are written as.
+// Documents of older versions are migrated when loaded.
+//
+//	1: properties with "ValueType" and "PropertyType" rather than "Type" and "Kind"
+//	2: enum members as a map of names to values, invocations without kinds
+//	3: enum members as an ordered list of members with integer values
+//	4: the backend of the service and the provenance of the elements of merged services
+//	5: links between the collections of different backends
+const CurrentFormatVersion = 5
+
+const ServiceTypeOData4 = "OData4"
+
+type TypeKind string
+
+const (
+	TypeKindEntityType TypeKind = "EntityType"
+	TypeKindStructure  TypeKind = "Structure"
+	TypeKindEnum       TypeKind = "Enum"
+)
+
+type PropertyKind string
+
+const (
+	PropertyKindPrimitive PropertyKind = "primitive"
+	PropertyKindRelation  PropertyKind = "relation"
+	PropertyKindStructure PropertyKind = "structure"
+	PropertyKindEnum      PropertyKind = "enum"
+	// Properties whose types couldn't be resolved
+	PropertyKindUnknown PropertyKind = "unknown"
+)
+
+type InvocationKind string
+
+const (
+	InvocationKindFunction InvocationKind = "Function"
+	InvocationKindAction   InvocationKind = "Action"
+)
+
+type BindingType string
+
+const (
+	BindingUnbound    BindingType = "unbound"
+	BindingEntity     BindingType = "entity"
+	BindingCollection BindingType = "collection"
+	// Invocations which are neither bound nor imported by the container
+	BindingUnknown BindingType = "unknown"
+)
+
+var (
+	typeKinds       = map[TypeKind]bool{TypeKindEntityType: true, TypeKindStructure: true, TypeKindEnum: true}
+	propertyKinds   = map[PropertyKind]bool{PropertyKindPrimitive: true, PropertyKindRelation: true, PropertyKindStructure: true, PropertyKindEnum: true, PropertyKindUnknown: true}
+	invocationKinds = map[InvocationKind]bool{InvocationKindFunction: true, InvocationKindAction: true}
+	bindingTypes    = map[BindingType]bool{BindingUnbound: true, BindingEntity: true, BindingCollection: true, BindingUnknown: true}
+)
+
+var ErrInvalidDocument MediationSchemaError = NewMediationSchemaError("invalid document", "the document isn't a mediation schema")
+
+type jsonDocument = map[string]interface{}
+
+// Migrations of the documents by the version they migrate from
+var migrations = map[int]func(document jsonDocument) error{
+	1: migrateFromVersion1,
+	2: migrateFromVersion2,
+	3: withoutChanges,
+	4: withoutChanges,
+}
+
+// The properties of the types and the arguments and results of the invocations of the document
+func documentProperties(document jsonDocument) []jsonDocument {
+	properties := []jsonDocument{}
+	types, _ := document["Types"].(jsonDocument)
+	for _, def := range types {
+		def, _ := def.(jsonDocument)
+		members, _ := def["Properties"].(jsonDocument)
+		for _, property := range members {
+			if property, ok := property.(jsonDocument); ok {
+				properties = append(properties, property)
+			}
+		}
+	}
+
+	invocations, _ := document["Invocations"].(jsonDocument)
+	for _, inv := range invocations {
+		inv, _ := inv.(jsonDocument)
+		arguments, _ := inv["Arguments"].([]interface{})
+		for _, argument := range arguments {
+			if argument, ok := argument.(jsonDocument); ok {
+				properties = append(properties, argument)
+			}
+		}
+		if result, ok := inv["Result"].(jsonDocument); ok {
+			properties = append(properties, result)
+		}
+	}
+	return properties
+}
+
+// Documents without a version are told apart by the names of the fields of their properties
+func detectFormatVersion(document jsonDocument) (int, error) {
+	raw, found := document["formatVersion"]
+	if !found {
+		for _, property := range documentProperties(document) {
+			if _, found := property["PropertyType"]; found {
+				return 1, nil
+			}
+		}
+		return 2, nil
+	}
+
+	version, ok := raw.(float64)
+	if !ok || version != float64(int(version)) || version < 1 {
+		return 0, ErrInvalidDocument.WithMessagef("$.formatVersion: '%v' is not a format version", raw)
+	}
+	if int(version) > CurrentFormatVersion {
+		return 0, ErrInvalidDocument.WithMessagef("$.formatVersion: version %d is newer than the supported version %d", int(version), CurrentFormatVersion)
+	}
+	return int(version), nil
+}
+
+func migrateFromVersion1(document jsonDocument) error {
+	for _, property := range documentProperties(document) {
+		if value, found := property["ValueType"]; found {
+			property["Type"] = value
+			delete(property, "ValueType")
+		}
+		if value, found := property["PropertyType"]; found {
+			property["Kind"] = value
+			delete(property, "PropertyType")
+		}
+	}
+	return nil
+}
+
+// Members are ordered by their values, as the order they were declared in is lost.
+// Functions and actions weren't told apart, so the invocations are taken as functions.
+func migrateFromVersion2(document jsonDocument) error {
+	invocations, _ := document["Invocations"].(jsonDocument)
+	for _, inv := range invocations {
+		if inv, ok := inv.(jsonDocument); ok {
+			if _, found := inv["Kind"]; !found {
+				inv["Kind"] = string(InvocationKindFunction)
+			}
+		}
+	}
+
+	types, _ := document["Types"].(jsonDocument)
+	for name, def := range types {
+		def, _ := def.(jsonDocument)
+		if def["Kind"] != string(TypeKindEnum) {
+			continue
+		}
+
+		values, _ := def["Members"].(jsonDocument)
+		descriptions, _ := def["MemberDescriptions"].(jsonDocument)
+		deprecations, _ := def["DeprecatedMembers"].(jsonDocument)
+
+		// Members without values were numbered in the order of their names
+		names := make([]string, 0, len(values))
+		for memberName := range values {
+			names = append(names, memberName)
+		}
+		sort.Strings(names)
+
+		parsed := make(map[string]int64)
+		for i, memberName := range names {
+			text, _ := values[memberName].(string)
+			parsed[memberName] = int64(i)
+			if text != "" {
+				value, err := strconv.ParseInt(text, 10, 64)
+				if err != nil {
+					return ErrInvalidDocument.WithMessagef("%s: '%s' is not an integer", memberPath(memberPath("$.Types", name)+".Members", memberName), text)
+				}
+				parsed[memberName] = value
+			}
+		}
+		sort.SliceStable(names, func(a, b int) bool {
+			return parsed[names[a]] < parsed[names[b]]
+		})
+
+		members := make([]interface{}, 0, len(names))
+		for _, memberName := range names {
+			member := jsonDocument{"Name": memberName, "Value": parsed[memberName]}
+			if description, found := descriptions[memberName]; found {
+				member["Description"] = description
+			}
+			if reason, found := deprecations[memberName]; found {
+				member["Deprecation"] = reason
+			}
+			members = append(members, member)
+		}
+
+		def["Members"] = members
+		delete(def, "MemberDescriptions")
+		delete(def, "DeprecatedMembers")
+	}
+	return nil
+}
+
+// Versions which only add optional fields read the documents of the previous version as they are.
+// Services of version 3 name no backend, which is left to whoever loads them as only they know it.
+func withoutChanges(document jsonDocument) error {
+	return nil
+}
+
+// Checks the kinds and binding types of the service, which are free text in JSON
+func checkKinds(service *Service) error {
+	checkProperty := func(path string, property Property) error {
+		if !propertyKinds[property.Kind] {
+			return ErrInvalidDocument.WithMessagef("%s.Kind: unknown property kind '%s'", path, property.Kind)
+		}
+		return nil
+	}
+
+	for name, def := range service.Types {
+		var properties map[string]Property
+		switch def.Kind {
+		case TypeKindEntityType:
+			properties = def.EntityType.Properties
+		case TypeKindStructure:
+			properties = def.Structure.Properties
+		}
+		for propertyName, property := range properties {
+			if err := checkProperty(memberPath(memberPath("$.Types", name)+".Properties", propertyName), property); err != nil {
+				return err
+			}
+		}
+	}
+
+	for name, inv := range service.Invocations {
+		path := memberPath("$.Invocations", name)
+		if !invocationKinds[inv.Kind] {
+			return ErrInvalidDocument.WithMessagef("%s.Kind: unknown invocation kind '%s'", path, inv.Kind)
+		}
+		if !bindingTypes[inv.BindingType] {
+			return ErrInvalidDocument.WithMessagef("%s.BindingType: unknown binding type '%s'", path, inv.BindingType)
+		}
+		for i, argument := range inv.Arguments {
+			if err := checkProperty(fmt.Sprintf("%s.Arguments[%d]", path, i), argument.Property); err != nil {
+				return err
+			}
+		}
+		if inv.Result != nil {
+			if err := checkProperty(fmt.Sprintf("%s.Result", path), *inv.Result); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Reads a service written as JSON, migrating documents of older versions to the current one.
+// Documents which aren't mediation schemas are rejected with ErrInvalidDocument rather than loaded partially.
+func Load(data []byte) (*Service, error) {
+	var document jsonDocument
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, ErrInvalidDocument.WithMessagef("%s", err)
+	}
+
+	version, err := detectFormatVersion(document)
+	if err != nil {
+		return nil, err
+	}
+	for ; version < CurrentFormatVersion; version++ {
+		if err := migrations[version](document); err != nil {
+			return nil, err
+		}
+	}
+	document["formatVersion"] = CurrentFormatVersion
+
+	migrated, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(migrated))
+	decoder.DisallowUnknownFields()
+	service := &Service{}
+	if err := decoder.Decode(service); err != nil {
+		return nil, ErrInvalidDocument.WithMessagef("%s", err)
+	}
+
+	if err := checkKinds(service); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
@@ -0,0 +1,188 @@
+package mediationschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+type jsonSchema = map[string]interface{}
+
+// Values of the string types which stand for kinds, listed in the schema so that consumers can rely on them
+var kindValues = map[reflect.Type][]string{
+	reflect.TypeOf(TypeKind("")):       sortedKeys(typeKinds),
+	reflect.TypeOf(PropertyKind("")):   sortedKeys(propertyKinds),
+	reflect.TypeOf(InvocationKind("")): sortedKeys(invocationKinds),
+	reflect.TypeOf(BindingType("")):    sortedKeys(bindingTypes),
+}
+
+type jsonSchemaBuilder struct {
+	definitions jsonSchema
+}
+
+// Name and options of the field in JSON, following the rules of encoding/json
+func jsonFieldName(field reflect.StructField) (string, bool, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	omitEmpty := false
+	for _, option := range parts[1:] {
+		omitEmpty = omitEmpty || option == "omitempty"
+	}
+	return name, omitEmpty, true
+}
+
+// Adds the fields of the struct to the properties, flattening the embedded structs as encoding/json does
+func (builder *jsonSchemaBuilder) addFields(t reflect.Type, properties jsonSchema, required *[]string) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := builder.addFields(field.Type, properties, required); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name, omitEmpty, ok := jsonFieldName(field)
+		if !ok {
+			continue
+		}
+		schema, err := builder.schemaOf(field.Type)
+		if err != nil {
+			return fmt.Errorf("field '%s' of %s: %w", field.Name, t, err)
+		}
+		// Nil pointers, slices and maps are written as null unless they're omitted
+		if !omitEmpty {
+			switch field.Type.Kind() {
+			case reflect.Ptr, reflect.Slice, reflect.Map:
+				schema = jsonSchema{"anyOf": []interface{}{schema, jsonSchema{"type": "null"}}}
+			}
+			*required = append(*required, name)
+		}
+		properties[name] = schema
+	}
+	return nil
+}
+
+func (builder *jsonSchemaBuilder) objectOf(t reflect.Type, extra jsonSchema) (jsonSchema, error) {
+	properties := jsonSchema{}
+	required := []string{}
+	for name, schema := range extra {
+		properties[name] = schema
+		required = append(required, name)
+	}
+	if err := builder.addFields(t, properties, &required); err != nil {
+		return nil, err
+	}
+	sort.Strings(required)
+
+	return jsonSchema{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}, nil
+}
+
+// Types are written as the definition of their kind along with the kind, see Type.MarshalJSON
+func (builder *jsonSchemaBuilder) typeSchema() (jsonSchema, error) {
+	variants := []interface{}{}
+	for _, variant := range []struct {
+		kind TypeKind
+		t    reflect.Type
+	}{
+		{TypeKindEntityType, reflect.TypeOf(EntityType{})},
+		{TypeKindStructure, reflect.TypeOf(Structure{})},
+		{TypeKindEnum, reflect.TypeOf(Enum{})},
+	} {
+		schema, err := builder.objectOf(variant.t, jsonSchema{"Kind": jsonSchema{"const": string(variant.kind)}})
+		if err != nil {
+			return nil, err
+		}
+		if schema["properties"].(jsonSchema)["Provenance"], err = builder.schemaOf(reflect.TypeOf(Provenance{})); err != nil {
+			return nil, err
+		}
+		variants = append(variants, schema)
+	}
+	return jsonSchema{"oneOf": variants}, nil
+}
+
+func (builder *jsonSchemaBuilder) schemaOf(t reflect.Type) (jsonSchema, error) {
+	if values, found := kindValues[t]; found {
+		return jsonSchema{"type": "string", "enum": values}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return builder.schemaOf(t.Elem())
+	case reflect.String:
+		return jsonSchema{"type": "string"}, nil
+	case reflect.Bool:
+		return jsonSchema{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return jsonSchema{"type": "integer"}, nil
+	case reflect.Slice:
+		items, err := builder.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return jsonSchema{"type": "array", "items": items}, nil
+	case reflect.Map:
+		values, err := builder.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return jsonSchema{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		ref := jsonSchema{"$ref": fmt.Sprintf("#/definitions/%s", t.Name())}
+		if _, found := builder.definitions[t.Name()]; found {
+			return ref, nil
+		}
+		// Registered before the fields, so that recursive types refer to themselves
+		builder.definitions[t.Name()] = nil
+		var definition jsonSchema
+		var err error
+		if t == reflect.TypeOf(Type{}) {
+			definition, err = builder.typeSchema()
+		} else {
+			definition, err = builder.objectOf(t, nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+		builder.definitions[t.Name()] = definition
+		return ref, nil
+	}
+	return nil, fmt.Errorf("no JSON schema for %s", t)
+}
+
+// The JSON Schema of the documents services are written as, generated from the Go types
+func JSONSchema() ([]byte, error) {
+	builder := &jsonSchemaBuilder{definitions: jsonSchema{}}
+
+	schema, err := builder.objectOf(reflect.TypeOf(Service{}), nil)
+	if err != nil {
+		return nil, err
+	}
+	// The version is fixed, as Load migrates the documents of older versions
+	schema["properties"].(jsonSchema)["formatVersion"] = jsonSchema{"const": CurrentFormatVersion}
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = "Mediation schema"
+	schema["description"] = fmt.Sprintf("Service mapped from OData metadata, format version %d", CurrentFormatVersion)
+	schema["definitions"] = builder.definitions
+
+	return json.MarshalIndent(schema, "", "  ")
+}
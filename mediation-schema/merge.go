@@ -0,0 +1,196 @@
+package mediationschema
+
+import (
+	"fmt"
+	"strings"
+)
+
+var ErrNameCollision MediationSchemaError = NewMediationSchemaError("name collision", "several backends define elements of the same name")
+
+type MergeOptions struct {
+	// Name of the merged service
+	Name string
+	// Prefixes by backend name. The names of the collections and invocations of the backend get prefixed,
+	// e.g. "People" becomes "TrippinPeople", and so do the namespaces of its types, e.g. "Trippin.Default.Person".
+	Prefixes map[string]string
+}
+
+// Renames the elements of a backend, rewriting the references between them
+type backendRenamer struct {
+	prefix string
+}
+
+func (r *backendRenamer) name(name string) string {
+	return r.prefix + name
+}
+
+func (r *backendRenamer) qualifiedName(qualifiedName string) string {
+	if r.prefix == "" {
+		return qualifiedName
+	}
+	return fmt.Sprintf("%s.%s", r.prefix, qualifiedName)
+}
+
+func (r *backendRenamer) optionalName(name *string, rename func(string) string) *string {
+	if name == nil {
+		return nil
+	}
+	renamed := rename(*name)
+	return &renamed
+}
+
+// Records the backend of the element along with its name there when it's renamed
+func newProvenance(backend string, name string, renamed string) *Provenance {
+	provenance := &Provenance{Backend: backend}
+	if renamed != name {
+		provenance.Name = &name
+	}
+	return provenance
+}
+
+func (r *backendRenamer) property(property Property) Property {
+	switch property.Kind {
+	case PropertyKindRelation, PropertyKindStructure, PropertyKindEnum:
+		property.Type = r.qualifiedName(property.Type)
+	}
+	property.RelationCollection = r.optionalName(property.RelationCollection, r.name)
+	property.TypeDefinition = r.optionalName(property.TypeDefinition, r.qualifiedName)
+	return property
+}
+
+func (r *backendRenamer) properties(properties map[string]Property) map[string]Property {
+	renamed := make(map[string]Property, len(properties))
+	for name, property := range properties {
+		renamed[name] = r.property(property)
+	}
+	return renamed
+}
+
+func (r *backendRenamer) typeDefinition(def Type) Type {
+	switch {
+	case def.EntityType != nil:
+		entityType := *def.EntityType
+		entityType.BaseType = r.optionalName(entityType.BaseType, r.qualifiedName)
+		entityType.Properties = r.properties(entityType.Properties)
+		def.EntityType = &entityType
+	case def.Structure != nil:
+		structure := *def.Structure
+		structure.Properties = r.properties(structure.Properties)
+		def.Structure = &structure
+	}
+	return def
+}
+
+func (r *backendRenamer) invocation(inv Invocation) Invocation {
+	inv.Name = r.name(inv.Name)
+	inv.ImportName = r.optionalName(inv.ImportName, r.name)
+	inv.BoundTo = r.optionalName(inv.BoundTo, r.qualifiedName)
+	inv.ResultCollection = r.optionalName(inv.ResultCollection, r.name)
+	arguments := make([]InvocationArgument, 0, len(inv.Arguments))
+	for _, argument := range inv.Arguments {
+		arguments = append(arguments, InvocationArgument{Name: argument.Name, Property: r.property(argument.Property)})
+	}
+	inv.Arguments = arguments
+	if inv.Result != nil {
+		result := r.property(*inv.Result)
+		inv.Result = &result
+	}
+	return inv
+}
+
+// The name an unbound invocation is exposed by, which must be unique across the backends
+func invocationEndpoint(inv Invocation) string {
+	if inv.ImportName != nil {
+		return *inv.ImportName
+	}
+	return inv.Name
+}
+
+// Backends of the elements by name, so that the names defined by several backends can be reported together
+type nameRegistry map[string][]string
+
+func (registry nameRegistry) add(name string, backend string) {
+	registry[name] = append(registry[name], backend)
+}
+
+func (registry nameRegistry) collisions(what string) []string {
+	collisions := []string{}
+	for _, name := range sortedKeys(registry) {
+		if backends := registry[name]; len(backends) > 1 {
+			collisions = append(collisions, fmt.Sprintf("%s '%s' (%s)", what, name, strings.Join(backends, ", ")))
+		}
+	}
+	return collisions
+}
+
+// Combines the services of several backends into one, recording the backend of every element so that the requests
+// for it can be routed there. Backends defining the same names collide unless they're told apart by prefixes.
+func Merge(services []*Service, options MergeOptions) (*Service, error) {
+	merged := &Service{
+		FormatVersion: CurrentFormatVersion,
+		Name:          options.Name,
+		Collections:   make(map[string]Collection),
+		Types:         make(map[string]Type),
+		Invocations:   make(map[string]Invocation),
+	}
+
+	backends := make(map[string]bool)
+	collections, types, invocations, endpoints := nameRegistry{}, nameRegistry{}, nameRegistry{}, nameRegistry{}
+	for _, service := range services {
+		backend := service.Backend
+		if backend == "" {
+			return nil, fmt.Errorf("service '%s' names no backend to merge it as", service.Name)
+		}
+		if backends[backend] {
+			return nil, fmt.Errorf("backend '%s' is merged more than once", backend)
+		}
+		backends[backend] = true
+		if merged.Type == "" {
+			merged.Type = service.Type
+		} else if merged.Type != service.Type {
+			return nil, fmt.Errorf("backend '%s' is of type '%s' rather than '%s'", backend, service.Type, merged.Type)
+		}
+
+		r := &backendRenamer{prefix: options.Prefixes[backend]}
+		for name, collection := range service.Collections {
+			renamed := r.name(name)
+			collection.Name = renamed
+			collection.EntityType = r.qualifiedName(collection.EntityType)
+			collection.Provenance = newProvenance(backend, name, renamed)
+			merged.Collections[renamed] = collection
+			collections.add(renamed, backend)
+		}
+		for name, def := range service.Types {
+			renamed := r.qualifiedName(name)
+			def = r.typeDefinition(def)
+			def.Provenance = newProvenance(backend, name, renamed)
+			merged.Types[renamed] = def
+			types.add(renamed, backend)
+		}
+		for name, inv := range service.Invocations {
+			renamed := r.qualifiedName(name)
+			renamedInv := r.invocation(inv)
+			renamedInv.Provenance = newProvenance(backend, name, renamed)
+			// Unbound invocations are requested by their import names rather than their qualified names
+			if inv.BindingType == BindingUnbound {
+				renamedInv.Provenance = newProvenance(backend, invocationEndpoint(inv), invocationEndpoint(renamedInv))
+				endpoints.add(invocationEndpoint(renamedInv), backend)
+			}
+			merged.Invocations[renamed] = renamedInv
+			invocations.add(renamed, backend)
+		}
+		for _, link := range service.Links {
+			link.SourceCollection = r.name(link.SourceCollection)
+			link.TargetCollection = r.name(link.TargetCollection)
+			merged.Links = append(merged.Links, link)
+		}
+	}
+
+	collisions := append(collections.collisions("collection"), types.collisions("type")...)
+	collisions = append(collisions, invocations.collisions("invocation")...)
+	collisions = append(collisions, endpoints.collisions("unbound invocation")...)
+	if len(collisions) > 0 {
+		return nil, ErrNameCollision.WithMessagef("%s", strings.Join(collisions, "; "))
+	}
+	return merged, nil
+}
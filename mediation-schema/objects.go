@@ -2,6 +2,7 @@ package mediationschema
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 
 	ods "github.com/kinvey/odata-schema/odata-schema"
@@ -19,23 +20,29 @@ type edmObjects struct {
 	entityTypes     map[string]*ods.EntityType
 	complexTypes    map[string]*ods.ComplexType
 	enumTypes       map[string]*ods.EnumType
+	typeDefinitions map[string]*ods.TypeDefinition
 	functions       map[string]*ods.Function
 	functionImports map[string]*ods.FunctionImport
 	actions         map[string]*ods.Action
 	actionImports   map[string]*ods.ActionImport
 	entityContainer *ods.EntityContainer
+	containerName   string
+	aliases         map[string]string
+	annotations     map[string][]ods.Annotation
+	// Qualified names of the containers the elements inherited through Extends are declared in, by element name
+	inheritedFrom map[string]string
 }
 
 func addToEntityTypes(objects edmObjects, schema *ods.Schema, entityType ods.EntityType) error {
 	namespacedName, aliasedName := formQualifiedName(schema, entityType.Name)
 	if _, ok := objects.entityTypes[namespacedName]; ok {
-		return ErrDuplicateDefinition.WithMessagef("duplicate entity type definition for entity type '%s'", namespacedName)
+		return errorAt(entityType.Position, ErrDuplicateDefinition.WithMessagef("duplicate entity type definition for entity type '%s'", namespacedName))
 	}
 
 	objects.entityTypes[namespacedName] = &entityType
 	if aliasedName != "" {
 		if _, ok := objects.entityTypes[aliasedName]; ok {
-			return ErrDuplicateDefinition.WithMessagef("duplicate entity type definition for entity type alias '%s'", aliasedName)
+			return errorAt(entityType.Position, ErrDuplicateDefinition.WithMessagef("duplicate entity type definition for entity type alias '%s'", aliasedName))
 		}
 
 		objects.entityTypes[aliasedName] = &entityType
@@ -47,13 +54,13 @@ func addToEntityTypes(objects edmObjects, schema *ods.Schema, entityType ods.Ent
 func addToComplexTypes(objects edmObjects, schema *ods.Schema, complexType ods.ComplexType) error {
 	namespacedName, aliasedName := formQualifiedName(schema, complexType.Name)
 	if _, ok := objects.complexTypes[namespacedName]; ok {
-		return ErrDuplicateDefinition.WithMessagef("duplicate complex type definition for complex type '%s'", namespacedName)
+		return errorAt(complexType.Position, ErrDuplicateDefinition.WithMessagef("duplicate complex type definition for complex type '%s'", namespacedName))
 	}
 
 	objects.complexTypes[namespacedName] = &complexType
 	if aliasedName != "" {
 		if _, ok := objects.complexTypes[aliasedName]; ok {
-			return ErrDuplicateDefinition.WithMessagef("duplicate complex type definition for complex type alias '%s'", aliasedName)
+			return errorAt(complexType.Position, ErrDuplicateDefinition.WithMessagef("duplicate complex type definition for complex type alias '%s'", aliasedName))
 		}
 
 		objects.complexTypes[aliasedName] = &complexType
@@ -65,13 +72,13 @@ func addToComplexTypes(objects edmObjects, schema *ods.Schema, complexType ods.C
 func addToEnumTypes(objects edmObjects, schema *ods.Schema, enumtype ods.EnumType) error {
 	namespacedName, aliasedName := formQualifiedName(schema, enumtype.Name)
 	if _, ok := objects.enumTypes[namespacedName]; ok {
-		return ErrDuplicateDefinition.WithMessagef("duplicate enum type definition for enum type '%s'", namespacedName)
+		return errorAt(enumtype.Position, ErrDuplicateDefinition.WithMessagef("duplicate enum type definition for enum type '%s'", namespacedName))
 	}
 
 	objects.enumTypes[namespacedName] = &enumtype
 	if aliasedName != "" {
 		if _, ok := objects.enumTypes[aliasedName]; ok {
-			return ErrDuplicateDefinition.WithMessagef("duplicate enum type definition for enum type alias '%s'", aliasedName)
+			return errorAt(enumtype.Position, ErrDuplicateDefinition.WithMessagef("duplicate enum type definition for enum type alias '%s'", aliasedName))
 		}
 
 		objects.enumTypes[aliasedName] = &enumtype
@@ -80,6 +87,24 @@ func addToEnumTypes(objects edmObjects, schema *ods.Schema, enumtype ods.EnumTyp
 	return nil
 }
 
+func addToTypeDefinitions(objects edmObjects, schema *ods.Schema, typeDefinition ods.TypeDefinition) error {
+	namespacedName, aliasedName := formQualifiedName(schema, typeDefinition.Name)
+	if _, ok := objects.typeDefinitions[namespacedName]; ok {
+		return errorAt(typeDefinition.Position, ErrDuplicateDefinition.WithMessagef("duplicate type definition for type '%s'", namespacedName))
+	}
+
+	objects.typeDefinitions[namespacedName] = &typeDefinition
+	if aliasedName != "" {
+		if _, ok := objects.typeDefinitions[aliasedName]; ok {
+			return errorAt(typeDefinition.Position, ErrDuplicateDefinition.WithMessagef("duplicate type definition for type alias '%s'", aliasedName))
+		}
+
+		objects.typeDefinitions[aliasedName] = &typeDefinition
+	}
+
+	return nil
+}
+
 func addToFunctions(objects edmObjects, schema *ods.Schema, function ods.Function) error {
 	namespacedName, aliasedName := formQualifiedName(schema, function.Name)
 	if _, ok := objects.functions[namespacedName]; !ok {
@@ -115,16 +140,44 @@ func extractObjects(backendName string, edm *ods.EdmxDocument) (*edmObjects, err
 		entityTypes:     make(map[string]*ods.EntityType),
 		complexTypes:    make(map[string]*ods.ComplexType),
 		enumTypes:       make(map[string]*ods.EnumType),
+		typeDefinitions: make(map[string]*ods.TypeDefinition),
 		functions:       make(map[string]*ods.Function),
 		actions:         make(map[string]*ods.Action),
 		functionImports: make(map[string]*ods.FunctionImport),
 		actionImports:   make(map[string]*ods.ActionImport),
 		entityContainer: nil,
+		inheritedFrom:   make(map[string]string),
+		aliases:         make(map[string]string),
+		annotations:     make(map[string][]ods.Annotation),
+	}
+
+	for _, reference := range edm.References {
+		for _, include := range reference.Includes {
+			if include.Alias != nil {
+				objects.aliases[*include.Alias] = include.Namespace
+			}
+		}
+	}
+
+	for _, schema := range edm.DataServices.Schemas {
+		if schema.Alias != nil {
+			objects.aliases[*schema.Alias] = schema.Namespace
+		}
 	}
 
+	containers := []declaredContainer{}
 	for _, schema := range edm.DataServices.Schemas {
-		if schema.EntityContainer != nil {
-			objects.entityContainer = schema.EntityContainer
+		for i := range schema.EntityContainers {
+			name, _ := formQualifiedName(&schema, schema.EntityContainers[i].Name)
+			containers = append(containers, declaredContainer{name: name, container: &schema.EntityContainers[i]})
+		}
+		for _, annotations := range schema.ExternalAnnotations {
+			// Only the annotations applying to every qualifier are considered
+			if annotations.Qualifier != nil {
+				continue
+			}
+			target := objects.normalizeTarget(annotations.Target)
+			objects.annotations[target] = append(objects.annotations[target], annotations.Annotations...)
 		}
 		for _, entityType := range schema.EntityTypes {
 			if err := addToEntityTypes(objects, &schema, entityType); err != nil {
@@ -144,6 +197,11 @@ func extractObjects(backendName string, edm *ods.EdmxDocument) (*edmObjects, err
 				}
 			}
 		}
+		for _, typeDefinition := range schema.TypeDefinitions {
+			if err := addToTypeDefinitions(objects, &schema, typeDefinition); err != nil {
+				return nil, err
+			}
+		}
 		for _, function := range schema.Functions {
 			if err := addToFunctions(objects, &schema, function); err != nil {
 				// TODO: handle function overloads
@@ -162,13 +220,156 @@ func extractObjects(backendName string, edm *ods.EdmxDocument) (*edmObjects, err
 		}
 	}
 
-	for _, functionImport := range objects.entityContainer.FunctionImports {
-		objects.functionImports[functionImport.Function] = &functionImport
+	if err := objects.resolveEntityContainer(containers); err != nil {
+		return nil, err
 	}
 
-	for _, actionImport := range objects.entityContainer.ActionImports {
-		objects.actionImports[actionImport.Action] = &actionImport
+	for i, functionImport := range objects.entityContainer.FunctionImports {
+		objects.functionImports[functionImport.Function] = &objects.entityContainer.FunctionImports[i]
+	}
+
+	for i, actionImport := range objects.entityContainer.ActionImports {
+		objects.actionImports[actionImport.Action] = &objects.entityContainer.ActionImports[i]
 	}
 
 	return &objects, nil
 }
+
+type declaredContainer struct {
+	name      string
+	container *ods.EntityContainer
+}
+
+// Picks the container of the service and merges the elements of the containers it extends into it.
+// The container of the service is either the default one or the only one no other container extends.
+// Documents without containers only define types, e.g. the ones shared by several services.
+func (objects *edmObjects) resolveEntityContainer(containers []declaredContainer) error {
+	if len(containers) == 0 {
+		objects.entityContainer = &ods.EntityContainer{}
+		return nil
+	}
+
+	byName := make(map[string]*ods.EntityContainer)
+	extended := make(map[string]bool)
+	for _, declared := range containers {
+		byName[declared.name] = declared.container
+		if declared.container.Extends != nil {
+			extended[objects.normalizeQualifiedName(*declared.container.Extends)] = true
+		}
+	}
+
+	candidates := []string{}
+	for _, declared := range containers {
+		if declared.container.IsDefault {
+			candidates = []string{declared.name}
+			break
+		}
+		if !extended[declared.name] {
+			candidates = append(candidates, declared.name)
+		}
+	}
+	if len(candidates) == 0 {
+		return ErrInvalidMetadata.WithMessagef("the entity containers extend each other in a cycle")
+	}
+	if len(candidates) > 1 {
+		names := make([]string, 0, len(containers))
+		for _, declared := range containers {
+			names = append(names, declared.name)
+		}
+		return ErrInvalidMetadata.WithMessagef("unable to tell which of the entity containers '%s' is the one of the service", strings.Join(names, "', '"))
+	}
+
+	objects.containerName = candidates[0]
+	objects.entityContainer = byName[objects.containerName]
+	if objects.entityContainer.Extends == nil {
+		return nil
+	}
+
+	merged := *objects.entityContainer
+	merged.EntitySets = append([]ods.EntitySet{}, merged.EntitySets...)
+	merged.AssociationSets = append([]ods.AssociationSet{}, merged.AssociationSets...)
+	merged.Singletons = append([]ods.Singleton{}, merged.Singletons...)
+	merged.FunctionImports = append([]ods.FunctionImport{}, merged.FunctionImports...)
+	merged.ActionImports = append([]ods.ActionImport{}, merged.ActionImports...)
+
+	visited := map[string]bool{objects.containerName: true}
+	for current := objects.entityContainer; current.Extends != nil; {
+		baseName := objects.normalizeQualifiedName(*current.Extends)
+		base, ok := byName[baseName]
+		if !ok {
+			return errorAt(current.Position, ErrInvalidMetadata.WithMessagef("entity container '%s' extended by '%s' was not defined", *current.Extends, current.Name))
+		}
+		if visited[baseName] {
+			return errorAt(current.Position, ErrInvalidMetadata.WithMessagef("the entity containers extended through '%s' form a cycle", *current.Extends))
+		}
+		visited[baseName] = true
+		objects.inherit(&merged, base, baseName)
+		current = base
+	}
+
+	objects.entityContainer = &merged
+	return nil
+}
+
+// Adds the elements of the base container the container doesn't declare itself
+func (objects *edmObjects) inherit(container *ods.EntityContainer, base *ods.EntityContainer, baseName string) {
+	names := make(map[string]bool)
+	for _, entitySet := range container.EntitySets {
+		names[entitySet.Name] = true
+	}
+	for _, associationSet := range container.AssociationSets {
+		names[associationSet.Name] = true
+	}
+	for _, singleton := range container.Singletons {
+		names[singleton.Name] = true
+	}
+	for _, functionImport := range container.FunctionImports {
+		names[functionImport.Name] = true
+	}
+	for _, actionImport := range container.ActionImports {
+		names[actionImport.Name] = true
+	}
+
+	inherits := func(name string) bool {
+		if names[name] {
+			return false
+		}
+		objects.inheritedFrom[name] = baseName
+		return true
+	}
+	for _, entitySet := range base.EntitySets {
+		if inherits(entitySet.Name) {
+			container.EntitySets = append(container.EntitySets, entitySet)
+		}
+	}
+	for _, associationSet := range base.AssociationSets {
+		if inherits(associationSet.Name) {
+			container.AssociationSets = append(container.AssociationSets, associationSet)
+		}
+	}
+	for _, singleton := range base.Singletons {
+		if inherits(singleton.Name) {
+			container.Singletons = append(container.Singletons, singleton)
+		}
+	}
+	for _, functionImport := range base.FunctionImports {
+		if inherits(functionImport.Name) {
+			container.FunctionImports = append(container.FunctionImports, functionImport)
+		}
+	}
+	for _, actionImport := range base.ActionImports {
+		if inherits(actionImport.Name) {
+			container.ActionImports = append(container.ActionImports, actionImport)
+		}
+	}
+}
+
+// Path of the element of the container for annotation targets, e.g. "Namespace.Container/People".
+// Inherited elements are annotated through the container declaring them.
+func (objects *edmObjects) containerTarget(name string) string {
+	containerName := objects.containerName
+	if declaring, ok := objects.inheritedFrom[name]; ok {
+		containerName = declaring
+	}
+	return fmt.Sprintf("%s/%s", containerName, name)
+}
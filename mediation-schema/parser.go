@@ -1,10 +1,12 @@
 package mediationschema
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
 	ods "github.com/kinvey/odata-schema/odata-schema"
+	"github.com/kinvey/odata-schema/utils"
 )
 
 const collectionPrefix = "Collection("
@@ -26,27 +28,37 @@ func mapEntityType(qualifiedName string, objects *edmObjects) (EntityType, error
 		Streamable: entityType.HasStream,
 		BaseType:   entityType.BaseType,
 		Structure: Structure{
-			Name:       entityType.Name,
-			Properties: make(map[string]Property),
-			OpenType:   entityType.OpenType,
+			Name:        entityType.Name,
+			Description: mapDescription(entityType.Annotations, entityType.Documentation, objects.normalizeTarget(qualifiedName), objects),
+			Properties:  make(map[string]Property),
+			OpenType:    entityType.OpenType,
 		},
 	}
 
-	addStructuralProperties(qualifiedName, objects, mappedType.Properties)
-	addNavProperties(qualifiedName, objects, mappedType.Properties)
+	if err := addStructuralProperties(qualifiedName, objects, mappedType.Properties); err != nil {
+		return EntityType{}, err
+	}
+	if err := addNavProperties(qualifiedName, objects, mappedType.Properties); err != nil {
+		return EntityType{}, err
+	}
 
 	return mappedType, nil
 }
 
 func mapCollection(entitySet ods.EntitySet, objects *edmObjects) (Collection, error) {
 	if _, ok := objects.entityTypes[entitySet.EntityType]; !ok {
-		return Collection{}, fmt.Errorf("unable to map collection. entity type '%s' was not defined", entitySet.EntityType)
+		return Collection{}, errorAt(entitySet.Position, fmt.Errorf("unable to map collection. entity type '%s' was not defined", entitySet.EntityType))
 	}
 
+	target := objects.containerTarget(entitySet.Name)
+	capabilities := mapCapabilities(entitySet, objects)
 	res := Collection{
-		Name:       entitySet.Name,
-		EntityType: entitySet.EntityType,
-		Streamable: objects.entityTypes[entitySet.EntityType].HasStream,
+		Name:         entitySet.Name,
+		EntityType:   entitySet.EntityType,
+		Streamable:   objects.entityTypes[entitySet.EntityType].HasStream,
+		Capabilities: &capabilities,
+		Description:  mapDescription(entitySet.Annotations, entitySet.Documentation, target, objects),
+		Deprecation:  objects.mapDeprecation(entitySet.Annotations, target),
 	}
 
 	return res, nil
@@ -59,13 +71,18 @@ func mapComplexType(qualifiedName string, objects *edmObjects) (Structure, error
 
 	complexType := objects.complexTypes[qualifiedName]
 	mappedType := Structure{
-		Name:       complexType.Name,
-		Properties: make(map[string]Property),
-		OpenType:   complexType.OpenType,
+		Name:        complexType.Name,
+		Description: mapDescription(complexType.Annotations, complexType.Documentation, objects.normalizeTarget(qualifiedName), objects),
+		Properties:  make(map[string]Property),
+		OpenType:    complexType.OpenType,
 	}
 
-	addStructuralProperties(qualifiedName, objects, mappedType.Properties)
-	addNavProperties(qualifiedName, objects, mappedType.Properties)
+	if err := addStructuralProperties(qualifiedName, objects, mappedType.Properties); err != nil {
+		return Structure{}, err
+	}
+	if err := addNavProperties(qualifiedName, objects, mappedType.Properties); err != nil {
+		return Structure{}, err
+	}
 
 	return mappedType, nil
 }
@@ -77,28 +94,120 @@ func mapEnumType(qualifiedName string, objects *edmObjects) (Enum, error) {
 
 	enum := objects.enumTypes[qualifiedName]
 
+	values, err := enum.MemberValues()
+	if err != nil {
+		return Enum{}, errorAt(enum.Position, ErrInvalidMetadata.WithMessagef("invalid enum type '%s': %s", qualifiedName, err))
+	}
+	// Edm.Int32 is the default underlying type, as specified in MC-CSDL
+	valuesType, err := mapEdmType(enum.Underlying())
+	if err != nil {
+		return Enum{}, errorAt(enum.Position, err)
+	}
+
 	eType := Enum{
 		Name:        enum.Name,
-		Members:     make(map[string]string),
-		ValuesType:  enum.UnderlyingType,
+		Members:     make([]EnumMember, 0, len(enum.Members)),
+		ValuesType:  valuesType,
 		Multiselect: enum.IsFlags,
+		Description: mapDescription(enum.Annotations, enum.Documentation, objects.normalizeTarget(qualifiedName), objects),
 	}
 
-	if eType.ValuesType == "" {
-		mappedType, _ := mapEdmType("Edm.Int32") // Specified in MC-CSDL
-		eType.ValuesType = mappedType
+	for i, member := range enum.Members {
+		memberTarget := objects.normalizeTarget(fmt.Sprintf("%s/%s", qualifiedName, member.Name))
+		eType.Members = append(eType.Members, EnumMember{
+			Name:        member.Name,
+			Value:       values[i],
+			Description: mapDescription(member.Annotations, member.Documentation, memberTarget, objects),
+			Deprecation: objects.mapDeprecation(member.Annotations, memberTarget),
+		})
 	}
 
-	for _, member := range enum.Members {
-		eType.Members[member.Name] = member.Value
+	return eType, nil
+}
+
+func mapInvocationResult(returnType *ods.ReturnType, objects *edmObjects) (*Property, error) {
+	if returnType == nil || returnType.Type == "" || returnType.Type == "Edm.Void" {
+		return nil, nil
 	}
 
-	return eType, nil
+	// TODO: use a different type for the result, not Property
+	prop, err := typeToProperty(returnType.Type, objects)
+	if err != nil {
+		return nil, errorAt(returnType.Position, err)
+	}
+
+	if returnType.Nullable != nil {
+		prop.Required = !*returnType.Nullable
+	}
+
+	return &prop, nil
+}
+
+func mapInvocationArgument(invocationName string, param ods.Parameter, objects *edmObjects) (InvocationArgument, error) {
+	prop, err := typeToProperty(param.Type, objects)
+	if err != nil {
+		return InvocationArgument{}, errorAt(param.Position, err)
+	}
+
+	paramTarget := objects.normalizeTarget(fmt.Sprintf("%s/%s", invocationName, param.Name))
+	prop.Description = mapDescription(param.Annotations, param.Documentation, paramTarget, objects)
+
+	if param.Nullable != nil {
+		prop.Required = !*param.Nullable
+	}
+
+	return InvocationArgument{
+		Name:     param.Name,
+		Property: prop,
+	}, nil
+}
+
+// Follows the navigation properties of an entity set path, e.g. "person/Trips/Trippin.Flight/Airline",
+// starting from the binding parameter. Returns nil when the path ends in contained entities.
+func resolveEntitySetPath(entitySetPath string, bindingArg *InvocationArgument, objects *edmObjects) *string {
+	segments := strings.Split(entitySetPath, "/")
+	if segments[0] != bindingArg.Name {
+		return nil
+	}
+
+	currentType := bindingArg.Type
+	collection := bindingArg.RelationCollection
+
+	for _, segment := range segments[1:] {
+		if _, isTypeCast := objects.entityTypes[segment]; isTypeCast {
+			currentType = segment
+			continue
+		}
+
+		var navProp *ods.NavigationProperty
+		for _, prop := range getTypeNavProperties(currentType, objects) {
+			if prop.Name == segment {
+				navProp = &prop
+				break
+			}
+		}
+		if navProp == nil {
+			return nil
+		}
+
+		targetType, _ := unwrapCollectionType(navProp.Type)
+		if target, bound := findNavigationTarget(currentType, *navProp, collection, objects); bound {
+			collection = target
+		} else if navProp.ContainsTarget {
+			collection = nil
+		} else if collections := findCollectionsByEntityType(targetType, objects); len(collections) > 0 {
+			collection = &collections[0]
+		} else {
+			collection = nil
+		}
+		currentType = targetType
+	}
+
+	return collection
 }
 
 func mapFunction(funcName string, function *ods.Function, objects *edmObjects) (Invocation, error) {
-	// TODO: use a different type for the result, not Property
-	funcResult, err := typeToProperty(function.ReturnType.Type, objects)
+	funcResult, err := mapInvocationResult(&function.ReturnType, objects)
 
 	if err != nil {
 		return Invocation{}, err
@@ -106,37 +215,51 @@ func mapFunction(funcName string, function *ods.Function, objects *edmObjects) (
 
 	inv := Invocation{
 		Name:             function.Name,
-		BindingType:      "unknown",
+		Kind:             InvocationKindFunction,
+		Description:      mapDescription(function.Annotations, function.Documentation, objects.normalizeTarget(funcName), objects),
+		Deprecation:      objects.mapDeprecation(function.Annotations, objects.normalizeTarget(funcName)),
+		BindingType:      BindingUnknown,
 		BoundDataPointer: function.EntitySetPath,
 		Arguments:        make([]InvocationArgument, len(function.Parameters)),
-		Result:           &funcResult,
+		Result:           funcResult,
 	}
 
 	for i, param := range function.Parameters {
-		if prop, err := typeToProperty(param.Type, objects); err != nil {
+		if arg, err := mapInvocationArgument(funcName, param, objects); err != nil {
 			return Invocation{}, err
 		} else {
-			inv.Arguments[i] = InvocationArgument{
-				Name:     param.Name,
-				Property: prop,
-			}
+			inv.Arguments[i] = arg
 		}
 	}
 
-	if _, found := objects.functionImports[funcName]; found && !function.IsBound {
-		inv.BindingType = "unbound"
+	if functionImport, found := objects.functionImports[funcName]; found && !function.IsBound {
+		inv.BindingType = BindingUnbound
+		inv.ImportName = &functionImport.Name
+		if inv.Description == nil {
+			importTarget := objects.containerTarget(functionImport.Name)
+			inv.Description = mapDescription(functionImport.Annotations, functionImport.Documentation, importTarget, objects)
+		}
+		if inv.Deprecation == nil {
+			inv.Deprecation = objects.mapDeprecation(functionImport.Annotations, objects.containerTarget(functionImport.Name))
+		}
+		if functionImport.EntitySet != "" {
+			inv.ResultCollection = &functionImport.EntitySet
+		}
 	} else if function.IsBound {
 		if entityType, err := typeToProperty(function.Parameters[0].Type, objects); err != nil {
-			return Invocation{}, err
+			return Invocation{}, errorAt(function.Parameters[0].Position, err)
 		} else {
 			if entityType.IsCollection {
-				inv.BindingType = "collection"
+				inv.BindingType = BindingCollection
 			} else {
-				inv.BindingType = "entity"
+				inv.BindingType = BindingEntity
 			}
 		}
 
 		inv.BoundTo = &inv.Arguments[0].Type
+		if function.EntitySetPath != nil {
+			inv.ResultCollection = resolveEntitySetPath(*function.EntitySetPath, &inv.Arguments[0], objects)
+		}
 	}
 
 	return inv, nil
@@ -144,51 +267,60 @@ func mapFunction(funcName string, function *ods.Function, objects *edmObjects) (
 
 // TODO: consolidate with mapFunction?
 func mapAction(actionName string, action *ods.Action, objects *edmObjects) (Invocation, error) {
-	var result *Property = nil
+	result, err := mapInvocationResult(action.ReturnType, objects)
 
-	if action.ReturnType != nil {
-		// TODO: use a different type for the result, not Property
-		if prop, err := typeToProperty(action.ReturnType.Type, objects); err != nil {
-			return Invocation{}, err
-		} else {
-			result = &prop
-		}
+	if err != nil {
+		return Invocation{}, err
 	}
 
 	inv := Invocation{
 		Name:             action.Name,
-		BindingType:      "unknown",
+		Kind:             InvocationKindAction,
+		Description:      mapDescription(action.Annotations, action.Documentation, objects.normalizeTarget(actionName), objects),
+		Deprecation:      objects.mapDeprecation(action.Annotations, objects.normalizeTarget(actionName)),
+		BindingType:      BindingUnknown,
 		BoundDataPointer: action.EntitySetPath,
 		Arguments:        make([]InvocationArgument, len(action.Parameters)),
 		Result:           result,
 	}
 
 	for i, param := range action.Parameters {
-		if prop, err := typeToProperty(param.Type, objects); err != nil {
+		if arg, err := mapInvocationArgument(actionName, param, objects); err != nil {
 			return Invocation{}, err
 		} else {
-			inv.Arguments[i] = InvocationArgument{
-				Name:     param.Name,
-				Property: prop,
-			}
+			inv.Arguments[i] = arg
 		}
 	}
 
-	if _, found := objects.actionImports[actionName]; found && !action.IsBound {
-		inv.BindingType = "unbound"
+	if actionImport, found := objects.actionImports[actionName]; found && !action.IsBound {
+		inv.BindingType = BindingUnbound
+		inv.ImportName = &actionImport.Name
+		if inv.Description == nil {
+			importTarget := objects.containerTarget(actionImport.Name)
+			inv.Description = mapDescription(actionImport.Annotations, actionImport.Documentation, importTarget, objects)
+		}
+		if inv.Deprecation == nil {
+			inv.Deprecation = objects.mapDeprecation(actionImport.Annotations, objects.containerTarget(actionImport.Name))
+		}
+		if actionImport.EntitySet != "" {
+			inv.ResultCollection = &actionImport.EntitySet
+		}
 	} else if action.IsBound {
 		if entityType, err := typeToProperty(action.Parameters[0].Type, objects); err != nil {
-			return Invocation{}, err
+			return Invocation{}, errorAt(action.Parameters[0].Position, err)
 		} else {
 			if entityType.IsCollection {
-				inv.BindingType = "collection"
+				inv.BindingType = BindingCollection
 			} else {
-				inv.BindingType = "entity"
+				inv.BindingType = BindingEntity
 			}
 		}
 
 		// inv.BoundTo = &action.Parameters[0].Type
 		inv.BoundTo = &inv.Arguments[0].Type
+		if action.EntitySetPath != nil {
+			inv.ResultCollection = resolveEntitySetPath(*action.EntitySetPath, &inv.Arguments[0], objects)
+		}
 	}
 
 	return inv, nil
@@ -196,11 +328,12 @@ func mapAction(actionName string, action *ods.Action, objects *edmObjects) (Invo
 
 func mapEDMObjectsToService(objects *edmObjects) (*Service, error) {
 	service := &Service{
-		Name:        objects.entityContainer.Name,
-		Type:        "OData4",
-		Collections: make(map[string]Collection),
-		Invocations: make(map[string]Invocation),
-		Types:       make(map[string]Type),
+		FormatVersion: CurrentFormatVersion,
+		Name:          objects.entityContainer.Name,
+		Type:          ServiceTypeOData4,
+		Collections:   make(map[string]Collection),
+		Invocations:   make(map[string]Invocation),
+		Types:         make(map[string]Type),
 	}
 
 	for _, entitySet := range objects.entityContainer.EntitySets {
@@ -216,7 +349,7 @@ func mapEDMObjectsToService(objects *edmObjects) (*Service, error) {
 			return nil, err
 		} else {
 			service.Types[name] = Type{
-				Kind:       "EntityType",
+				Kind:       TypeKindEntityType,
 				EntityType: &et,
 			}
 		}
@@ -227,7 +360,7 @@ func mapEDMObjectsToService(objects *edmObjects) (*Service, error) {
 			return nil, err
 		} else {
 			service.Types[name] = Type{
-				Kind:      "Structure",
+				Kind:      TypeKindStructure,
 				Structure: &ct,
 			}
 		}
@@ -238,7 +371,7 @@ func mapEDMObjectsToService(objects *edmObjects) (*Service, error) {
 			return nil, err
 		} else {
 			service.Types[name] = Type{
-				Kind: "Enum",
+				Kind: TypeKindEnum,
 				Enum: &enum,
 			}
 		}
@@ -293,7 +426,7 @@ func mapEdmType(edmType string) (string, error) {
 		return "float32", nil
 	case "Edm.Double":
 		return "float64", nil
-	case "Edm.Int16":
+	case "Edm.Byte", "Edm.SByte", "Edm.Int16":
 		return "int16", nil
 	case "Edm.Int32":
 		return "int32", nil
@@ -313,7 +446,7 @@ func mapEdmType(edmType string) (string, error) {
 		return "duration", nil
 	default:
 		if strings.HasPrefix(edmType, "Edm.") {
-			panic(edmType)
+			return "", ErrUnsupportedType.WithMessagef("'%s' can't be mapped", edmType)
 		}
 		return "", fmt.Errorf("unknown Type: %s", edmType)
 	}
@@ -329,45 +462,196 @@ func findCollectionsByEntityType(qualifiedName string, objects *edmObjects) []st
 	return result
 }
 
+func unwrapCollectionType(typeName string) (string, bool) {
+	if strings.HasPrefix(typeName, collectionPrefix) && strings.HasSuffix(typeName, ")") {
+		return typeName[len(collectionPrefix) : len(typeName)-1], true
+	}
+	return typeName, false
+}
+
+func isContainedEntityType(qualifiedName string, objects *edmObjects) bool {
+	for typeName := &qualifiedName; typeName != nil; {
+		for _, entityType := range objects.entityTypes {
+			for _, navProp := range entityType.NavigationProperties {
+				if targetType, _ := unwrapCollectionType(navProp.Type); navProp.ContainsTarget && targetType == *typeName {
+					return true
+				}
+			}
+		}
+		if entityType, ok := objects.entityTypes[*typeName]; ok {
+			typeName = entityType.BaseType
+		} else {
+			typeName = nil
+		}
+	}
+	return false
+}
+
+// The type followed by its base types, nearest first
+func typeChain(qualifiedName string, objects *edmObjects) []string {
+	chain := []string{}
+	visited := make(map[string]bool)
+	for typeName := &qualifiedName; typeName != nil && !visited[*typeName]; {
+		visited[*typeName] = true
+		chain = append(chain, *typeName)
+		if entityType, ok := objects.entityTypes[*typeName]; ok {
+			typeName = entityType.BaseType
+		} else {
+			typeName = nil
+		}
+	}
+	return chain
+}
+
+// Finds the target of the navigation property among the bindings of the entity set. The binding path is either
+// the name of the navigation property or the name prefixed with a type cast to one of the types of the chain.
+func bindingTarget(entitySet ods.EntitySet, chain []string, navProp ods.NavigationProperty) *string {
+	for _, binding := range entitySet.NavigationPropertyBindings {
+		matches := binding.Path == navProp.Name
+		if slash := strings.LastIndex(binding.Path, "/"); slash >= 0 && binding.Path[slash+1:] == navProp.Name {
+			matches = utils.SliceContainsString(chain, binding.Path[:slash])
+		}
+		if matches {
+			target := binding.Target[strings.LastIndex(binding.Target, "/")+1:]
+			return &target
+		}
+	}
+	return nil
+}
+
+// Finds the entity set targeted by a navigation property of the given type through the navigation property bindings
+// of the entity container. The bindings of the entity set owning the entity are used when it's known. Otherwise the
+// entity sets of the type or of its nearest base type with a binding are looked at, and the target is only taken when
+// they agree on it, as it varies by entity set otherwise. Tells whether any binding was found, in which case the target
+// is nil when it varies.
+func findNavigationTarget(qualifiedName string, navProp ods.NavigationProperty, entitySet *string, objects *edmObjects) (*string, bool) {
+	chain := typeChain(qualifiedName, objects)
+	if entitySet != nil {
+		for _, es := range objects.entityContainer.EntitySets {
+			if es.Name == *entitySet {
+				target := bindingTarget(es, chain, navProp)
+				return target, target != nil
+			}
+		}
+	}
+
+	for _, typeName := range chain {
+		var target *string
+		for _, es := range objects.entityContainer.EntitySets {
+			if es.EntityType != typeName {
+				continue
+			}
+			if found := bindingTarget(es, chain, navProp); found != nil {
+				if target != nil && *target != *found {
+					return nil, true
+				}
+				target = found
+			}
+		}
+		if target != nil {
+			return target, true
+		}
+	}
+	return nil, false
+}
+
+func getReferentialConstraints(navProp ods.NavigationProperty, objects *edmObjects) []ReferentialConstraint {
+	constraints := []ReferentialConstraint{}
+	for _, rc := range navProp.ReferentialConstraints {
+		constraints = append(constraints, ReferentialConstraint{
+			Property:           rc.Property,
+			ReferencedProperty: rc.ReferencedProperty,
+		})
+	}
+
+	// The dependent side declares the constraints, so the principal side reads them from its partner
+	if len(constraints) == 0 && navProp.Partner != nil {
+		targetType, _ := unwrapCollectionType(navProp.Type)
+		for _, partner := range getTypeNavProperties(targetType, objects) {
+			if partner.Name != *navProp.Partner {
+				continue
+			}
+			for _, rc := range partner.ReferentialConstraints {
+				constraints = append(constraints, ReferentialConstraint{
+					Property:           rc.ReferencedProperty,
+					ReferencedProperty: rc.Property,
+				})
+			}
+		}
+	}
+
+	return constraints
+}
+
 func typeToProperty(typeName string, objects *edmObjects) (Property, error) {
 	result := Property{
-		Kind:         "unknown",
+		Kind:         PropertyKindUnknown,
 		Type:         fmt.Sprintf("unknown (%s)", typeName),
 		IsCollection: false,
 	}
 	if mappedType, err := mapEdmType(typeName); err == nil {
-		result.Kind = "primitive"
+		result.Kind = PropertyKindPrimitive
 		result.Type = mappedType
-	} else if strings.HasPrefix(typeName, collectionPrefix) {
-		actualType := typeName[len(collectionPrefix) : len(typeName)-1]
+	} else if errors.Is(err, ErrUnsupportedType) {
+		return Property{}, err
+	} else if actualType, isCollection := unwrapCollectionType(typeName); isCollection {
 		if mapped, err := typeToProperty(actualType, objects); err != nil {
 			return Property{}, err
 		} else {
 			mapped.IsCollection = true
 			result = mapped
 		}
+	} else if typeDefinition, ok := objects.typeDefinitions[typeName]; ok {
+		// Type definitions are mapped to their underlying types, remembering their names and facets
+		mappedType, err := mapEdmType(typeDefinition.UnderlyingType)
+		if err != nil {
+			return Property{}, errorAt(typeDefinition.Position, fmt.Errorf("invalid underlying type of type definition '%s': %w", typeName, err))
+		}
+		qualifiedName := objects.normalizeQualifiedName(typeName)
+		result.Kind = PropertyKindPrimitive
+		result.Type = mappedType
+		result.TypeDefinition = &qualifiedName
+		result.Facets = mapFacets(typeDefinition)
 	} else if _, ok := objects.entityTypes[typeName]; ok {
 		result.Type = typeName
-		result.Kind = "relation"
+		result.Kind = PropertyKindRelation
 		collections := findCollectionsByEntityType(typeName, objects)
 		if len(collections) == 0 {
-			return Property{}, fmt.Errorf("unable to find collection for entity type '%s'", typeName)
+			// Contained entities are only reachable through the navigation property which contains them
+			if !isContainedEntityType(typeName, objects) {
+				return Property{}, fmt.Errorf("unable to find collection for entity type '%s'", typeName)
+			}
+		} else {
+			// TODO: return some sort of ambiguity descriptor when len(collections) > 1
+			// else if len(collections) > 1 {
+			// 	return Property{}, fmt.Errorf("unable to find collection for entity type '%s'", typeName)
+			// }
+			result.RelationCollection = &collections[0]
 		}
-		// TODO: return some sort of ambiguity descriptor when len(collections) > 1
-		// else if len(collections) > 1 {
-		// 	return Property{}, fmt.Errorf("unable to find collection for entity type '%s'", typeName)
-		// }
-		result.RelationCollection = &collections[0]
 	} else if _, ok := objects.complexTypes[typeName]; ok {
-		result.Kind = "structure"
+		result.Kind = PropertyKindStructure
 		result.Type = typeName
 	} else if _, ok := objects.enumTypes[typeName]; ok {
-		result.Kind = "enum"
+		result.Kind = PropertyKindEnum
 		result.Type = typeName
 	}
 	return result, nil
 }
 
+func mapFacets(typeDefinition *ods.TypeDefinition) *Facets {
+	facets := Facets{
+		MaxLength: typeDefinition.MaxLength,
+		Precision: typeDefinition.Precision,
+		Scale:     typeDefinition.Scale,
+		SRID:      typeDefinition.SRID,
+		Unicode:   typeDefinition.Unicode,
+	}
+	if facets == (Facets{}) {
+		return nil
+	}
+	return &facets
+}
+
 func getTypeStructuralProperties(qualifiedName string, objects *edmObjects) []ods.Property {
 	var properties []ods.Property
 	var baseType *string
@@ -407,13 +691,19 @@ func addStructuralProperties(typeName string, objects *edmObjects, result map[st
 	for _, property := range properties {
 		prop, err := typeToProperty(property.Type, objects)
 		if err != nil {
-			return err
+			return errorAt(property.Position, err)
 		}
 		if property.Nullable != nil {
 			prop.Required = !*property.Nullable
 		} else {
 			prop.Required = false
 		}
+		propertyTarget := objects.normalizeTarget(fmt.Sprintf("%s/%s", typeName, property.Name))
+		prop.Description = mapDescription(property.Annotations, property.Documentation, propertyTarget, objects)
+		prop.Computed = objects.hasTag(property.Annotations, propertyTarget, coreNamespace+".Computed")
+		prop.Immutable = objects.hasTag(property.Annotations, propertyTarget, coreNamespace+".Immutable")
+		prop.ReadOnly = objects.isReadOnly(property.Annotations, propertyTarget)
+		prop.Deprecation = objects.mapDeprecation(property.Annotations, propertyTarget)
 		result[property.Name] = prop
 	}
 	return nil
@@ -422,8 +712,21 @@ func addStructuralProperties(typeName string, objects *edmObjects, result map[st
 func addNavProperties(qualifiedName string, objects *edmObjects, result map[string]Property) error {
 	for _, property := range getTypeNavProperties(qualifiedName, objects) {
 		if prop, err := typeToProperty(property.Type, objects); err != nil {
-			return err
+			return errorAt(property.Position, err)
 		} else {
+			navigationPath := property.Name
+			prop.NavigationPath = &navigationPath
+			propertyTarget := objects.normalizeTarget(fmt.Sprintf("%s/%s", qualifiedName, property.Name))
+			prop.Description = mapDescription(property.Annotations, property.Documentation, propertyTarget, objects)
+			prop.Deprecation = objects.mapDeprecation(property.Annotations, propertyTarget)
+			if target, bound := findNavigationTarget(qualifiedName, property, nil, objects); bound {
+				prop.RelationCollection = target
+			} else if property.ContainsTarget {
+				prop.RelationCollection = nil
+			}
+			if constraints := getReferentialConstraints(property, objects); len(constraints) > 0 {
+				prop.ReferentialConstraints = constraints
+			}
 			result[property.Name] = prop
 		}
 	}
@@ -443,14 +746,39 @@ func getTypeKeys(qualifiedName string, objects *edmObjects) ([]string, error) {
 	} else if src.BaseType != nil {
 		return getTypeKeys(*src.BaseType, objects)
 	} else {
-		return []string{}, fmt.Errorf("unable to find keys for type '%s'", qualifiedName)
+		return []string{}, errorAt(src.Position, fmt.Errorf("unable to find keys for type '%s'", qualifiedName))
 	}
 }
 
+// Diagnostics of the metadata which the mapping can't work around, as opposed to the ones it tolerates, e.g. invalid names
+var blockingDiagnostics = map[string]bool{
+	ods.DiagnosticMissingKey:     true,
+	ods.DiagnosticMissingBinding: true,
+}
+
+func validateForMapping(edm *ods.EdmxDocument) error {
+	messages := []string{}
+	for _, diagnostic := range ods.Validate(edm) {
+		if diagnostic.Severity == ods.SeverityError && blockingDiagnostics[diagnostic.Code] {
+			messages = append(messages, diagnostic.String())
+		}
+	}
+	if len(messages) > 0 {
+		return ErrInvalidMetadata.WithMessagef("%s", strings.Join(messages, "; "))
+	}
+	return nil
+}
+
 func Parse(backendName string, edm *ods.EdmxDocument) (*Service, error) {
+	if err := validateForMapping(edm); err != nil {
+		return nil, err
+	}
 	if objects, err := extractObjects(backendName, edm); err != nil {
 		return nil, err
+	} else if service, err := mapEDMObjectsToService(objects); err != nil {
+		return nil, err
 	} else {
-		return mapEDMObjectsToService(objects)
+		service.Backend = backendName
+		return service, nil
 	}
 }
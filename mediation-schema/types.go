@@ -2,21 +2,54 @@ package mediationschema
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
 )
 
 type Service struct {
-	Name        string
-	Type        string
+	// Version of the format of the document, CurrentFormatVersion for the services mapped by this package
+	FormatVersion int `json:"formatVersion"`
+	Name          string
+	Type          string
+	// Name of the backend the service is mapped from, empty for services merged from several backends
+	Backend     string `json:",omitempty"`
 	Collections map[string]Collection
 	Types       map[string]Type
 	Invocations map[string]Invocation
+	// Relations between the collections of different backends
+	Links []Link `json:",omitempty"`
+}
+
+// A relation the backends know nothing about, joining the entities of the source collection with the entities
+// of the target collection whose TargetProperty holds the value of their SourceProperty, e.g. a product and the news item
+// of its ContentItemId
+type Link struct {
+	// Name of the relation on the entity type of the source collection
+	Name             string
+	SourceCollection string
+	SourceProperty   string
+	TargetCollection string
+	// Usually the key of the target entity type
+	TargetProperty string
+	// Whether several targets may hold the value, as when the target property isn't a key
+	IsCollection bool    `json:",omitempty"`
+	Description  *string `json:",omitempty"`
+}
+
+// Where an element of a service merged from several backends comes from
+type Provenance struct {
+	Backend string
+	// Name the backend knows the element by, when the merge prefixed it.
+	// Unbound invocations are known by their import names.
+	Name *string `json:",omitempty"`
 }
 
 type Type struct {
-	Kind       string
+	Kind       TypeKind
 	EntityType *EntityType `json:",omitempty"`
 	Structure  *Structure  `json:",omitempty"`
 	Enum       *Enum       `json:",omitempty"`
+	Provenance *Provenance `json:",omitempty"`
 }
 
 type InvocationArgument struct {
@@ -26,11 +59,17 @@ type InvocationArgument struct {
 
 type Invocation struct {
 	Name             string
-	BindingType      string
+	Kind             InvocationKind
+	Description      *string `json:",omitempty"`
+	Deprecation      *string `json:",omitempty"`
+	ImportName       *string `json:",omitempty"`
+	BindingType      BindingType
 	BoundTo          *string `json:",omitempty"`
 	BoundDataPointer *string `json:",omitempty"`
 	Arguments        []InvocationArgument
 	Result           *Property
+	ResultCollection *string     `json:",omitempty"`
+	Provenance       *Provenance `json:",omitempty"`
 }
 
 type EntityType struct {
@@ -41,73 +80,207 @@ type EntityType struct {
 }
 
 type Collection struct {
-	Name       string
-	EntityType string
-	Streamable bool `json:",omitempty"`
+	Name         string
+	EntityType   string
+	Description  *string       `json:",omitempty"`
+	Deprecation  *string       `json:",omitempty"`
+	Streamable   bool          `json:",omitempty"`
+	Capabilities *Capabilities `json:",omitempty"`
+	Provenance   *Provenance   `json:",omitempty"`
+}
+
+// Operations supported by a collection. Collections without capabilities support all of them.
+type Capabilities struct {
+	Insertable              bool
+	Updatable               bool
+	Deletable               bool
+	Filterable              bool
+	NonFilterableProperties []string `json:",omitempty"`
+	Sortable                bool
+	NonSortableProperties   []string `json:",omitempty"`
+	Expandable              bool
+	NonExpandableProperties []string `json:",omitempty"`
+	Countable               bool
 }
 
 type Structure struct {
-	Name       string
-	OpenType   bool `json:",omitempty"`
-	Properties map[string]Property
+	Name        string
+	Description *string `json:",omitempty"`
+	OpenType    bool    `json:",omitempty"`
+	Properties  map[string]Property
 }
 
 type Enum struct {
-	Name        string
-	ValuesType  string
+	Name       string
+	ValuesType string
+	// Whether a value may combine several members, i.e. the enum type is a flags enum type
 	Multiselect bool `json:",omitempty"`
-	Members     map[string]string
+	// The members in the order they're declared
+	Members     []EnumMember
+	Description *string `json:",omitempty"`
+}
+
+type EnumMember struct {
+	Name        string
+	Value       int64
+	Description *string `json:",omitempty"`
+	Deprecation *string `json:",omitempty"`
+}
+
+func (enum *Enum) member(name string) *EnumMember {
+	for i := range enum.Members {
+		if enum.Members[i].Name == name {
+			return &enum.Members[i]
+		}
+	}
+	return nil
+}
+
+// The value standing for the combination of the members, e.g. 3 for "Read" (1) and "Write" (2).
+// Values of enums which aren't multiselect are made of a single member.
+func (enum *Enum) Compose(names []string) (int64, error) {
+	if !enum.Multiselect && len(names) != 1 {
+		return 0, fmt.Errorf("a value of enum '%s' is made of a single member", enum.Name)
+	}
+
+	var value int64
+	for _, name := range names {
+		member := enum.member(name)
+		if member == nil {
+			return 0, fmt.Errorf("enum '%s' has no member '%s'", enum.Name, name)
+		}
+		value |= member.Value
+	}
+	return value, nil
+}
+
+// The members the value combines, in the order they're declared. Members made of several flags, e.g. "ReadWrite" (3),
+// are preferred over the flags they're made of, and zero is the member with that value if there's one.
+func (enum *Enum) Decompose(value int64) ([]string, error) {
+	if !enum.Multiselect || value == 0 {
+		for _, member := range enum.Members {
+			if member.Value == value {
+				return []string{member.Name}, nil
+			}
+		}
+		if value == 0 && enum.Multiselect {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("enum '%s' has no member with value %d", enum.Name, value)
+	}
+
+	// Larger values cover more flags, so they're taken first
+	indexes := make([]int, 0, len(enum.Members))
+	for i, member := range enum.Members {
+		if member.Value > 0 {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		return enum.Members[indexes[a]].Value > enum.Members[indexes[b]].Value
+	})
+
+	remaining := value
+	picked := []int{}
+	for _, i := range indexes {
+		if flags := enum.Members[i].Value; remaining&flags == flags {
+			picked = append(picked, i)
+			remaining &^= flags
+		}
+	}
+	if remaining != 0 {
+		return nil, fmt.Errorf("value %d of enum '%s' isn't a combination of its members", value, enum.Name)
+	}
+
+	sort.Ints(picked)
+	names := make([]string, 0, len(picked))
+	for _, i := range picked {
+		names = append(names, enum.Members[i].Name)
+	}
+	return names, nil
+}
+
+// Property on the declaring type that holds the value of ReferencedProperty on the related type
+type ReferentialConstraint struct {
+	Property           string
+	ReferencedProperty string
 }
 
 type Property struct {
-	Type               string
-	Kind               string
-	RelationCollection *string `json:",omitempty"`
-	IsCollection       bool    `json:",omitempty"`
-	Required           bool    `json:",omitempty"`
+	Type                   string
+	Kind                   PropertyKind
+	Description            *string                 `json:",omitempty"`
+	Deprecation            *string                 `json:",omitempty"`
+	RelationCollection     *string                 `json:",omitempty"`
+	NavigationPath         *string                 `json:",omitempty"`
+	ReferentialConstraints []ReferentialConstraint `json:",omitempty"`
+	IsCollection           bool                    `json:",omitempty"`
+	Required               bool                    `json:",omitempty"`
+	Computed               bool                    `json:",omitempty"`
+	Immutable              bool                    `json:",omitempty"`
+	ReadOnly               bool                    `json:",omitempty"`
+	// Qualified name of the type definition the primitive type comes from, e.g. "Trippin.Money"
+	TypeDefinition *string `json:",omitempty"`
+	Facets         *Facets `json:",omitempty"`
+}
+
+// Facets of a type definition restricting the values of its underlying type
+type Facets struct {
+	MaxLength *string `json:",omitempty"`
+	Precision *string `json:",omitempty"`
+	Scale     *string `json:",omitempty"`
+	SRID      *string `json:",omitempty"`
+	Unicode   *bool   `json:",omitempty"`
 }
 
 type entityTypeSerializer struct {
-	Kind string
+	Kind TypeKind
 	EntityType
+	Provenance *Provenance `json:",omitempty"`
 }
 
 type structureSerializer struct {
-	Kind string
+	Kind TypeKind
 	Structure
+	Provenance *Provenance `json:",omitempty"`
 }
 
 type enumSerializer struct {
-	Kind string
+	Kind TypeKind
 	Enum
+	Provenance *Provenance `json:",omitempty"`
 }
 
 func (td Type) MarshalJSON() ([]byte, error) {
-	switch td.Kind {
-	case "EntityType":
+	switch {
+	case td.Kind == TypeKindEntityType && td.EntityType != nil:
 		ser := entityTypeSerializer{
 			Kind:       td.Kind,
 			EntityType: *td.EntityType,
+			Provenance: td.Provenance,
 		}
 		return json.Marshal(ser)
-	case "Structure":
+	case td.Kind == TypeKindStructure && td.Structure != nil:
 		ser := structureSerializer{
-			Kind:      td.Kind,
-			Structure: *td.Structure,
+			Kind:       td.Kind,
+			Structure:  *td.Structure,
+			Provenance: td.Provenance,
 		}
 		return json.Marshal(ser)
-	case "Enum":
+	case td.Kind == TypeKindEnum && td.Enum != nil:
 		ser := enumSerializer{
-			Kind: td.Kind,
-			Enum: *td.Enum,
+			Kind:       td.Kind,
+			Enum:       *td.Enum,
+			Provenance: td.Provenance,
 		}
 		return json.Marshal(ser)
 	}
-	panic("should not happen")
+	return nil, fmt.Errorf("type of kind '%s' has no definition of its kind", td.Kind)
 }
 
 type kindness struct {
-	Kind string
+	Kind       TypeKind
+	Provenance *Provenance
 }
 
 func (td *Type) UnmarshalJSON(b []byte) error {
@@ -118,11 +291,12 @@ func (td *Type) UnmarshalJSON(b []byte) error {
 	}
 
 	td.Kind = k.Kind
+	td.Provenance = k.Provenance
 
 	switch td.Kind {
 	default:
-		panic("unknown kind - cannot deserialize")
-	case "EntityType":
+		return fmt.Errorf("unknown type kind '%s'", td.Kind)
+	case TypeKindEntityType:
 		{
 			deser := EntityType{}
 			if err := json.Unmarshal(b, &deser); err != nil {
@@ -130,7 +304,7 @@ func (td *Type) UnmarshalJSON(b []byte) error {
 			}
 			td.EntityType = &deser
 		}
-	case "Structure":
+	case TypeKindStructure:
 		{
 			deser := Structure{}
 			if err := json.Unmarshal(b, &deser); err != nil {
@@ -138,7 +312,7 @@ func (td *Type) UnmarshalJSON(b []byte) error {
 			}
 			td.Structure = &deser
 		}
-	case "Enum":
+	case TypeKindEnum:
 		{
 			deser := Enum{}
 			if err := json.Unmarshal(b, &deser); err != nil {
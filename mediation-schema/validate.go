@@ -0,0 +1,285 @@
+package mediationschema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Types of the primitive properties, as mapped by mapEdmType
+var primitiveTypes = map[string]bool{
+	"string": true, "boolean": true, "date": true, "datetime": true, "float32": true, "float64": true,
+	"int16": true, "int32": true, "int64": true, "decimal": true, "binary": true, "stream": true,
+	"geopoint": true, "duration": true,
+}
+
+// A reference of the service which doesn't resolve, or a definition which contradicts itself
+type IntegrityError struct {
+	// JSON path of the offending value, e.g. `$.Types["Trippin.Person"].Properties["Friends"].Type`
+	Path    string
+	Message string
+}
+
+func (e IntegrityError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+func memberPath(path string, name string) string {
+	return fmt.Sprintf("%s[%q]", path, name)
+}
+
+type integrityChecker struct {
+	service *Service
+	errors  []IntegrityError
+}
+
+func (checker *integrityChecker) report(path string, format string, args ...interface{}) {
+	checker.errors = append(checker.errors, IntegrityError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Checks that the name refers to a type of the kind
+func (checker *integrityChecker) checkTypeRef(path string, name string, kind TypeKind) bool {
+	def, found := checker.service.Types[name]
+	if !found {
+		checker.report(path, "type '%s' is not defined", name)
+		return false
+	}
+	if def.Kind != kind {
+		checker.report(path, "type '%s' is of kind '%s' rather than '%s'", name, def.Kind, kind)
+		return false
+	}
+	return true
+}
+
+func (checker *integrityChecker) checkCollectionRef(path string, name string) {
+	if _, found := checker.service.Collections[name]; !found {
+		checker.report(path, "collection '%s' is not defined", name)
+	}
+}
+
+// Properties of the structured type, looking at the base types of entity types as well
+func (checker *integrityChecker) lookupProperty(typeName string, name string) (Property, bool) {
+	visited := make(map[string]bool)
+	for typeName != "" && !visited[typeName] {
+		visited[typeName] = true
+		def := checker.service.Types[typeName]
+		switch {
+		case def.EntityType != nil:
+			if property, found := def.EntityType.Properties[name]; found {
+				return property, true
+			}
+			typeName = ""
+			if def.EntityType.BaseType != nil {
+				typeName = *def.EntityType.BaseType
+			}
+		case def.Structure != nil:
+			property, found := def.Structure.Properties[name]
+			return property, found
+		default:
+			return Property{}, false
+		}
+	}
+	return Property{}, false
+}
+
+func (checker *integrityChecker) findProperty(typeName string, name string) bool {
+	_, found := checker.lookupProperty(typeName, name)
+	return found
+}
+
+func (checker *integrityChecker) checkProperty(path string, declaringType string, property Property) {
+	typePath := path + ".Type"
+	switch property.Kind {
+	case PropertyKindPrimitive:
+		if !primitiveTypes[property.Type] {
+			checker.report(typePath, "'%s' is not a primitive type", property.Type)
+		}
+	case PropertyKindRelation:
+		if !checker.checkTypeRef(typePath, property.Type, TypeKindEntityType) {
+			return
+		}
+		if property.RelationCollection != nil {
+			checker.checkCollectionRef(path+".RelationCollection", *property.RelationCollection)
+		}
+		for i, constraint := range property.ReferentialConstraints {
+			constraintPath := fmt.Sprintf("%s.ReferentialConstraints[%d]", path, i)
+			if declaringType != "" && !checker.findProperty(declaringType, constraint.Property) {
+				checker.report(constraintPath+".Property", "property '%s' is not defined on type '%s'", constraint.Property, declaringType)
+			}
+			if !checker.findProperty(property.Type, constraint.ReferencedProperty) {
+				checker.report(constraintPath+".ReferencedProperty", "property '%s' is not defined on type '%s'", constraint.ReferencedProperty, property.Type)
+			}
+		}
+	case PropertyKindStructure:
+		checker.checkTypeRef(typePath, property.Type, TypeKindStructure)
+	case PropertyKindEnum:
+		checker.checkTypeRef(typePath, property.Type, TypeKindEnum)
+	case PropertyKindUnknown:
+		// Invocations typed with unknown types are left out of the GraphQL schema, while types can't do without their properties
+		if declaringType != "" {
+			checker.report(typePath, "'%s' is not a known type", property.Type)
+		}
+	default:
+		checker.report(path+".Kind", "unknown property kind '%s'", property.Kind)
+	}
+}
+
+func (checker *integrityChecker) checkProperties(path string, declaringType string, properties map[string]Property) {
+	for _, name := range sortedKeys(properties) {
+		checker.checkProperty(memberPath(path+".Properties", name), declaringType, properties[name])
+	}
+}
+
+func (checker *integrityChecker) checkType(name string, def Type) {
+	path := memberPath("$.Types", name)
+	switch def.Kind {
+	case TypeKindEntityType:
+		if def.EntityType == nil {
+			checker.report(path, "the entity type has no definition")
+			return
+		}
+		if len(def.EntityType.Key) == 0 {
+			checker.report(path+".Key", "the entity type has no key")
+		}
+		for i, key := range def.EntityType.Key {
+			// Keys may be paths into structured properties, e.g. "Address/City"
+			property := strings.SplitN(key, "/", 2)[0]
+			if !checker.findProperty(name, property) {
+				checker.report(fmt.Sprintf("%s.Key[%d]", path, i), "key property '%s' is not defined", key)
+			}
+		}
+		if def.EntityType.BaseType != nil {
+			checker.checkTypeRef(path+".BaseType", *def.EntityType.BaseType, TypeKindEntityType)
+		}
+		checker.checkProperties(path, name, def.EntityType.Properties)
+	case TypeKindStructure:
+		if def.Structure == nil {
+			checker.report(path, "the structure has no definition")
+			return
+		}
+		checker.checkProperties(path, name, def.Structure.Properties)
+	case TypeKindEnum:
+		if def.Enum == nil {
+			checker.report(path, "the enum has no definition")
+			return
+		}
+		names := make(map[string]bool)
+		for i, member := range def.Enum.Members {
+			if names[member.Name] {
+				checker.report(fmt.Sprintf("%s.Members[%d]", path, i), "member '%s' is defined more than once", member.Name)
+			}
+			names[member.Name] = true
+		}
+	default:
+		checker.report(path+".Kind", "unknown type kind '%s'", def.Kind)
+	}
+}
+
+func (checker *integrityChecker) checkInvocation(name string, inv Invocation) {
+	path := memberPath("$.Invocations", name)
+	switch inv.BindingType {
+	case BindingEntity, BindingCollection:
+		if inv.BoundTo == nil {
+			checker.report(path+".BoundTo", "the bound invocation has no entity type it is bound to")
+		} else {
+			checker.checkTypeRef(path+".BoundTo", *inv.BoundTo, TypeKindEntityType)
+		}
+		// The first argument is the binding parameter
+		if len(inv.Arguments) == 0 {
+			checker.report(path+".Arguments", "the bound invocation has no argument for the binding parameter")
+		}
+	case BindingUnbound, BindingUnknown:
+	default:
+		checker.report(path+".BindingType", "unknown binding type '%s'", inv.BindingType)
+	}
+
+	for i, argument := range inv.Arguments {
+		checker.checkProperty(fmt.Sprintf("%s.Arguments[%d]", path, i), "", argument.Property)
+	}
+	if inv.Result != nil {
+		checker.checkProperty(path+".Result", "", *inv.Result)
+	}
+	if inv.ResultCollection != nil {
+		checker.checkCollectionRef(path+".ResultCollection", *inv.ResultCollection)
+	}
+}
+
+// Looks up the property of the entity type of the collection the link joins on
+func (checker *integrityChecker) checkLinkEnd(path string, collectionName string, propertyName string) (Property, string, bool) {
+	collection, found := checker.service.Collections[collectionName]
+	if !found {
+		checker.report(path+"Collection", "collection '%s' is not defined", collectionName)
+		return Property{}, "", false
+	}
+	property, found := checker.lookupProperty(collection.EntityType, propertyName)
+	if !found {
+		checker.report(path+"Property", "property '%s' is not defined on type '%s'", propertyName, collection.EntityType)
+		return Property{}, "", false
+	}
+	if property.IsCollection || (property.Kind != PropertyKindPrimitive && property.Kind != PropertyKindEnum) {
+		checker.report(path+"Property", "property '%s' doesn't hold a single primitive or enum value to join on", propertyName)
+		return Property{}, "", false
+	}
+	return property, collection.EntityType, true
+}
+
+func (checker *integrityChecker) checkLink(index int, link Link, linkNames map[string]bool) {
+	path := fmt.Sprintf("$.Links[%d]", index)
+	source, sourceType, sourceFound := checker.checkLinkEnd(path+".Source", link.SourceCollection, link.SourceProperty)
+	target, _, targetFound := checker.checkLinkEnd(path+".Target", link.TargetCollection, link.TargetProperty)
+	if !sourceFound {
+		return
+	}
+
+	if link.Name == "" {
+		checker.report(path+".Name", "the link has no name")
+	} else if checker.findProperty(sourceType, link.Name) {
+		checker.report(path+".Name", "'%s' is already a property of type '%s'", link.Name, sourceType)
+	} else if linkNames[sourceType+"/"+link.Name] {
+		checker.report(path+".Name", "type '%s' has more than one link named '%s'", sourceType, link.Name)
+	}
+	linkNames[sourceType+"/"+link.Name] = true
+
+	if targetFound && (source.Kind != target.Kind || source.Type != target.Type) {
+		checker.report(path, "the %s property '%s' of type '%s' can't be joined with the %s property '%s' of type '%s'",
+			source.Kind, link.SourceProperty, source.Type, target.Kind, link.TargetProperty, target.Type)
+	}
+}
+
+// Checks that the types, collections, keys, bindings and links the service refers to are defined, reporting all the references
+// which aren't along with their JSON paths. Services which pass can be turned into GraphQL schemas.
+func Validate(service *Service) []IntegrityError {
+	checker := &integrityChecker{service: service, errors: []IntegrityError{}}
+
+	for _, name := range sortedKeys(service.Collections) {
+		collection := service.Collections[name]
+		path := memberPath("$.Collections", name)
+		if collection.Name != name {
+			checker.report(path+".Name", "the collection is named '%s' rather than '%s'", collection.Name, name)
+		}
+		checker.checkTypeRef(path+".EntityType", collection.EntityType, TypeKindEntityType)
+	}
+	for _, name := range sortedKeys(service.Types) {
+		checker.checkType(name, service.Types[name])
+	}
+	for _, name := range sortedKeys(service.Invocations) {
+		checker.checkInvocation(name, service.Invocations[name])
+	}
+	linkNames := make(map[string]bool)
+	for i, link := range service.Links {
+		checker.checkLink(i, link, linkNames)
+	}
+
+	return checker.errors
+}
+
+// Keys of the map with string keys in order, so that things are reported in the same order every time
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	for _, key := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,131 @@
+package odataschema
+
+import "encoding/xml"
+
+// Constant and path expressions of annotations and property values, in both attribute and element notation
+type Expression struct {
+	Binary                        *string               `xml:"Binary,attr,omitempty"`
+	Bool                          *string               `xml:"Bool,attr,omitempty"`
+	Date                          *string               `xml:"Date,attr,omitempty"`
+	DateTimeOffset                *string               `xml:"DateTimeOffset,attr,omitempty"`
+	Decimal                       *string               `xml:"Decimal,attr,omitempty"`
+	Duration                      *string               `xml:"Duration,attr,omitempty"`
+	EnumMember                    *string               `xml:"EnumMember,attr,omitempty"`
+	Float                         *string               `xml:"Float,attr,omitempty"`
+	Guid                          *string               `xml:"Guid,attr,omitempty"`
+	Int                           *string               `xml:"Int,attr,omitempty"`
+	String                        *string               `xml:"String,attr,omitempty"`
+	TimeOfDay                     *string               `xml:"TimeOfDay,attr,omitempty"`
+	AnnotationPath                *string               `xml:"AnnotationPath,attr,omitempty"`
+	NavigationPropertyPath        *string               `xml:"NavigationPropertyPath,attr,omitempty"`
+	Path                          *string               `xml:"Path,attr,omitempty"`
+	PropertyPath                  *string               `xml:"PropertyPath,attr,omitempty"`
+	BoolElement                   *string               `xml:"Bool"`
+	IntElement                    *string               `xml:"Int"`
+	StringElement                 *string               `xml:"String"`
+	EnumMemberElement             *string               `xml:"EnumMember"`
+	PathElement                   *string               `xml:"Path"`
+	PropertyPathElement           *string               `xml:"PropertyPath"`
+	NavigationPropertyPathElement *string               `xml:"NavigationPropertyPath"`
+	Record                        *Record               `xml:"Record"`
+	Collection                    *CollectionExpression `xml:"Collection"`
+}
+
+type CollectionExpression struct {
+	XMLName                 xml.Name `xml:"Collection"`
+	Strings                 []string `xml:"String"`
+	Ints                    []string `xml:"Int"`
+	EnumMembers             []string `xml:"EnumMember"`
+	Paths                   []string `xml:"Path"`
+	PropertyPaths           []string `xml:"PropertyPath"`
+	NavigationPropertyPaths []string `xml:"NavigationPropertyPath"`
+	Records                 []Record `xml:"Record"`
+}
+
+type PropertyValue struct {
+	XMLName  xml.Name `xml:"PropertyValue"`
+	Property string   `xml:"Property,attr,omitempty"`
+	Expression
+}
+
+type Record struct {
+	XMLName        xml.Name        `xml:"Record"`
+	Type           *string         `xml:"Type,attr,omitempty"`
+	PropertyValues []PropertyValue `xml:"PropertyValue"`
+	Annotations    []Annotation    `xml:"Annotation"`
+}
+
+type Annotation struct {
+	XMLName   xml.Name `xml:"Annotation"`
+	Term      string   `xml:"Term,attr,omitempty"`
+	Qualifier *string  `xml:"Qualifier,attr,omitempty"`
+	Expression
+}
+
+// Annotations applied to a model element from outside of it
+type Annotations struct {
+	XMLName     xml.Name     `xml:"Annotations"`
+	Target      string       `xml:"Target,attr,omitempty"`
+	Qualifier   *string      `xml:"Qualifier,attr,omitempty"`
+	Annotations []Annotation `xml:"Annotation"`
+}
+
+func firstNonNil(values ...*string) *string {
+	for _, value := range values {
+		if value != nil {
+			return value
+		}
+	}
+	return nil
+}
+
+func (e *Expression) BoolValue() (bool, bool) {
+	if value := firstNonNil(e.Bool, e.BoolElement); value != nil {
+		return *value == "true", true
+	}
+	return false, false
+}
+
+func (e *Expression) StringValue() (string, bool) {
+	if value := firstNonNil(e.String, e.StringElement); value != nil {
+		return *value, true
+	}
+	return "", false
+}
+
+// CSDL JSON renders enum members as strings, so strings are taken as enum members as well
+func (e *Expression) EnumMemberValue() (string, bool) {
+	if value := firstNonNil(e.EnumMember, e.EnumMemberElement, e.String, e.StringElement); value != nil {
+		return *value, true
+	}
+	return "", false
+}
+
+// The path of any of the path expressions
+func (e *Expression) PathValue() (string, bool) {
+	value := firstNonNil(e.Path, e.PathElement, e.PropertyPath, e.PropertyPathElement,
+		e.NavigationPropertyPath, e.NavigationPropertyPathElement, e.AnnotationPath)
+	if value != nil {
+		return *value, true
+	}
+	return "", false
+}
+
+// The strings and paths of a collection expression, which lists properties either way
+func (c *CollectionExpression) Values() []string {
+	values := []string{}
+	values = append(values, c.Strings...)
+	values = append(values, c.Paths...)
+	values = append(values, c.PropertyPaths...)
+	values = append(values, c.NavigationPropertyPaths...)
+	return values
+}
+
+func (r *Record) PropertyValue(property string) *PropertyValue {
+	for i := range r.PropertyValues {
+		if r.PropertyValues[i].Property == property {
+			return &r.PropertyValues[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package odataschema
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Ranges of the integer types enum types may be based on
+var enumRanges = map[string][2]int64{
+	"Edm.Byte":  {0, math.MaxUint8},
+	"Edm.SByte": {math.MinInt8, math.MaxInt8},
+	"Edm.Int16": {math.MinInt16, math.MaxInt16},
+	"Edm.Int32": {math.MinInt32, math.MaxInt32},
+	"Edm.Int64": {math.MinInt64, math.MaxInt64},
+}
+
+// The underlying type of the enum type, Edm.Int32 when not specified
+func (enumType *EnumType) Underlying() string {
+	if enumType.UnderlyingType == "" {
+		return "Edm.Int32"
+	}
+	return enumType.UnderlyingType
+}
+
+// Parses the value of an enum member, checking that it's in the range of the underlying type
+func ParseEnumValue(value string, underlyingType string) (int64, error) {
+	bounds, ok := enumRanges[underlyingType]
+	if !ok {
+		return 0, fmt.Errorf("underlying type '%s' is not an integer type", underlyingType)
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || parsed < bounds[0] || parsed > bounds[1] {
+		return 0, fmt.Errorf("value '%s' is not in the range of %s", value, underlyingType)
+	}
+	return parsed, nil
+}
+
+// Values of the members in the order they're declared. Members are numbered from 0 when none of them has a value,
+// otherwise all of them must have one. The members of flags enum types must all have non-negative values.
+func (enumType *EnumType) MemberValues() ([]int64, error) {
+	if _, ok := enumRanges[enumType.Underlying()]; !ok {
+		return nil, fmt.Errorf("underlying type '%s' is not an integer type", enumType.Underlying())
+	}
+
+	valued := 0
+	for _, member := range enumType.Members {
+		if member.Value != "" {
+			valued++
+		}
+	}
+	if enumType.IsFlags && valued < len(enumType.Members) {
+		return nil, fmt.Errorf("the members of a flags enum type must all have values")
+	}
+	if valued > 0 && valued < len(enumType.Members) {
+		return nil, fmt.Errorf("either all or none of the members must have values")
+	}
+
+	values := make([]int64, len(enumType.Members))
+	for i, member := range enumType.Members {
+		if valued == 0 {
+			values[i] = int64(i)
+			continue
+		}
+		value, err := ParseEnumValue(member.Value, enumType.Underlying())
+		if err != nil {
+			return nil, fmt.Errorf("member '%s': %w", member.Name, err)
+		}
+		if enumType.IsFlags && value < 0 {
+			return nil, fmt.Errorf("member '%s': the values of a flags enum type can't be negative", member.Name)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,213 @@
+package odataschema
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type FetchOptions struct {
+	// Headers sent with every request, e.g. "Authorization"
+	Headers map[string]string
+	// No timeout when zero
+	Timeout time.Duration
+	// Directory the documents are cached in along with their ETags, so that unchanged ones aren't downloaded again.
+	// Nothing is cached when empty.
+	CacheDir string
+	// Whether to fetch the documents the metadata references as well
+	FollowReferences bool
+	// The client to send the requests with, http.DefaultClient when nil
+	Client *http.Client
+}
+
+type FetchedMetadata struct {
+	Document *EdmxDocument
+	// The referenced documents by their URL
+	References map[string]*EdmxDocument
+}
+
+// The standard vocabularies are referenced by most services, and their terms are known without fetching them
+func isVocabularyReference(reference Reference) bool {
+	if len(reference.Includes) == 0 {
+		return false
+	}
+	for _, include := range reference.Includes {
+		if !strings.HasPrefix(include.Namespace, "Org.OData.") {
+			return false
+		}
+	}
+	return true
+}
+
+func cachePaths(cacheDir string, documentURL string) (string, string) {
+	hash := sha1.Sum([]byte(documentURL))
+	name := hex.EncodeToString(hash[:])
+	return filepath.Join(cacheDir, name+".metadata"), filepath.Join(cacheDir, name+".etag")
+}
+
+// Servers answer unknown paths and logins with HTML pages, which aren't worth parsing. Documents without a content type are parsed.
+func isDocumentContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/xml" || mediaType == "text/xml" || mediaType == "application/json" || strings.HasSuffix(mediaType, "+xml")
+}
+
+// Downloads the document, sending the ETag of the cached copy so that the server can tell it's unchanged.
+// Also returns the URL the document was fetched from in the end, which redirects may have changed.
+func fetchDocument(client *http.Client, documentURL string, options FetchOptions) ([]byte, *url.URL, error) {
+	request, err := http.NewRequest(http.MethodGet, documentURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	request.Header.Set("Accept", "application/xml")
+	for name, value := range options.Headers {
+		request.Header.Set(name, value)
+	}
+
+	var documentPath, etagPath string
+	if options.CacheDir != "" {
+		documentPath, etagPath = cachePaths(options.CacheDir, documentURL)
+		if etag, err := ioutil.ReadFile(etagPath); err == nil {
+			if _, err := os.Stat(documentPath); err == nil {
+				request.Header.Set("If-None-Match", string(etag))
+			}
+		}
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to fetch '%s': %w", documentURL, err)
+	}
+	defer response.Body.Close()
+	fetchedURL := response.Request.URL
+
+	if response.StatusCode == http.StatusNotModified && documentPath != "" {
+		data, err := ioutil.ReadFile(documentPath)
+		return data, fetchedURL, err
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, nil, fmt.Errorf("unable to fetch '%s': %s", documentURL, response.Status)
+	}
+	if contentType := response.Header.Get("Content-Type"); !isDocumentContentType(contentType) {
+		return nil, nil, fmt.Errorf("unable to fetch '%s': unexpected content type '%s'", documentURL, contentType)
+	}
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to fetch '%s': %w", documentURL, err)
+	}
+
+	if options.CacheDir != "" {
+		if err := os.MkdirAll(options.CacheDir, 0755); err != nil {
+			return nil, nil, err
+		}
+		if err := ioutil.WriteFile(documentPath, data, 0644); err != nil {
+			return nil, nil, err
+		}
+		if etag := response.Header.Get("ETag"); etag != "" {
+			if err := ioutil.WriteFile(etagPath, []byte(etag), 0644); err != nil {
+				return nil, nil, err
+			}
+		} else {
+			os.Remove(etagPath)
+		}
+	}
+
+	return data, fetchedURL, nil
+}
+
+// Fetches and parses the documents the document references, and the ones those reference in turn
+func fetchReferences(client *http.Client, documentURL *url.URL, edm *EdmxDocument, options FetchOptions, references map[string]*EdmxDocument) error {
+	for _, reference := range edm.References {
+		if isVocabularyReference(reference) {
+			continue
+		}
+
+		referenceURL, err := documentURL.Parse(reference.Uri)
+		if err != nil {
+			return fmt.Errorf("invalid reference '%s': %w", reference.Uri, err)
+		}
+		if _, ok := references[referenceURL.String()]; ok {
+			continue
+		}
+
+		data, fetchedURL, err := fetchDocument(client, referenceURL.String(), options)
+		if err != nil {
+			return err
+		}
+		referenced, err := ParseBytes(data)
+		if err != nil {
+			return fmt.Errorf("unable to parse '%s': %w", referenceURL, err)
+		}
+
+		references[referenceURL.String()] = referenced
+		if err := fetchReferences(client, fetchedURL, referenced, options, references); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The metadata of the service is at {serviceRoot}/$metadata. Service roots naming the metadata already are kept as they are,
+// and so are their query strings, e.g. the keys some services require.
+func serviceMetadataURL(serviceRoot string) (*url.URL, error) {
+	documentURL, err := url.Parse(serviceRoot)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(documentURL.Path, "/$metadata") {
+		documentURL.Path = strings.TrimSuffix(documentURL.Path, "/") + "/$metadata"
+		documentURL.RawPath = ""
+	}
+	return documentURL, nil
+}
+
+// Fetches the metadata of the service at {serviceRoot}/$metadata
+func FetchMetadata(serviceRoot string, options FetchOptions) (*FetchedMetadata, error) {
+	metadataURL, err := serviceMetadataURL(serviceRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	client := http.DefaultClient
+	if options.Client != nil {
+		client = options.Client
+	}
+	if options.Timeout > 0 {
+		withTimeout := *client
+		withTimeout.Timeout = options.Timeout
+		client = &withTimeout
+	}
+
+	data, fetchedURL, err := fetchDocument(client, metadataURL.String(), options)
+	if err != nil {
+		return nil, err
+	}
+	edm, err := ParseBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse '%s': %w", metadataURL, err)
+	}
+
+	metadata := &FetchedMetadata{Document: edm, References: make(map[string]*EdmxDocument)}
+	if options.FollowReferences {
+		// References are relative to where the document ended up after redirects
+		if err := fetchReferences(client, fetchedURL, edm, options, metadata.References); err != nil {
+			return nil, err
+		}
+	}
+
+	return metadata, nil
+}
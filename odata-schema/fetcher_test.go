@@ -0,0 +1,137 @@
+package odataschema
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const fetchedMetadata = `<?xml version="1.0" encoding="utf-8"?>
+<edmx:Edmx Version="4.0" xmlns:edmx="http://docs.oasis-open.org/odata/ns/edmx">
+  <edmx:Reference Uri="Shared.xml">
+    <edmx:Include Namespace="Shared" />
+  </edmx:Reference>
+  <edmx:DataServices>
+    <Schema Namespace="Fetched" xmlns="http://docs.oasis-open.org/odata/ns/edm" />
+  </edmx:DataServices>
+</edmx:Edmx>`
+
+const sharedMetadata = `<?xml version="1.0" encoding="utf-8"?>
+<edmx:Edmx Version="4.0" xmlns:edmx="http://docs.oasis-open.org/odata/ns/edmx">
+  <edmx:DataServices>
+    <Schema Namespace="Shared" xmlns="http://docs.oasis-open.org/odata/ns/edm" />
+  </edmx:DataServices>
+</edmx:Edmx>`
+
+func serveXML(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.Write([]byte(body))
+	}
+}
+
+func TestFetchMetadataURL(t *testing.T) {
+	requested := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.RequestURI())
+		serveXML(sharedMetadata)(w, r)
+	}))
+	defer server.Close()
+
+	cases := map[string]string{
+		"":                      "/$metadata",
+		"/":                     "/$metadata",
+		"/service":              "/service/$metadata",
+		"/service/":             "/service/$metadata",
+		"/service/$metadata":    "/service/$metadata",
+		"/service/?key=secret":  "/service/$metadata?key=secret",
+		"/$metadata?key=secret": "/$metadata?key=secret",
+	}
+	for path, expected := range cases {
+		requested = requested[:0]
+		if _, err := FetchMetadata(server.URL+path, FetchOptions{}); err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if len(requested) != 1 || requested[0] != expected {
+			t.Errorf("%s: expected a request for '%s', got %v", path, expected, requested)
+		}
+	}
+}
+
+func TestFetchMetadataFollowsRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/old/$metadata", http.RedirectHandler("/new/$metadata", http.StatusMovedPermanently))
+	mux.HandleFunc("/new/$metadata", serveXML(fetchedMetadata))
+	mux.HandleFunc("/new/Shared.xml", serveXML(sharedMetadata))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	metadata, err := FetchMetadata(server.URL+"/old", FetchOptions{FollowReferences: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Document.DataServices.Schemas[0].Namespace != "Fetched" {
+		t.Errorf("expected the redirected document to be fetched")
+	}
+	// The reference is relative to the document it was redirected to
+	if _, found := metadata.References[server.URL+"/new/Shared.xml"]; !found || len(metadata.References) != 1 {
+		t.Errorf("expected the reference to be fetched next to the redirected document, got %v", metadata.References)
+	}
+}
+
+func TestFetchMetadataFailures(t *testing.T) {
+	cases := map[string]struct {
+		handler  http.HandlerFunc
+		expected string
+	}{
+		"not found": {
+			handler:  http.NotFound,
+			expected: "404 Not Found",
+		},
+		"server error": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "failed", http.StatusInternalServerError)
+			},
+			expected: "500 Internal Server Error",
+		},
+		"login page": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.Write([]byte("<html><body>Sign in</body></html>"))
+			},
+			expected: "unexpected content type 'text/html; charset=utf-8'",
+		},
+		"redirect loop": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, r.URL.Path, http.StatusFound)
+			},
+			expected: "stopped after 10 redirects",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(c.handler)
+			defer server.Close()
+
+			_, err := FetchMetadata(server.URL, FetchOptions{})
+			if err == nil || !strings.Contains(err.Error(), c.expected) {
+				t.Errorf("expected an error containing '%s', got %v", c.expected, err)
+			}
+		})
+	}
+}
+
+func TestFetchMetadataReferenceFailure(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/$metadata", serveXML(fetchedMetadata))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	_, err := FetchMetadata(server.URL, FetchOptions{FollowReferences: true})
+	if err == nil || !strings.Contains(err.Error(), "/Shared.xml': 404 Not Found") {
+		t.Errorf("expected the missing reference to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,919 @@
+package odataschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Member of a JSON object. The objects are kept as lists of members, as the order of the elements matters.
+type jsonMember struct {
+	name  string
+	value json.RawMessage
+}
+
+type jsonObject []jsonMember
+
+func decodeJSONObject(data json.RawMessage) (jsonObject, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil {
+		return nil, err
+	} else if token != json.Delim('{') {
+		return nil, fmt.Errorf("expected a JSON object, got '%v'", token)
+	}
+
+	object := jsonObject{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		object = append(object, jsonMember{name: token.(string), value: value})
+	}
+
+	return object, nil
+}
+
+func jsonKind(value json.RawMessage) byte {
+	trimmed := bytes.TrimSpace(value)
+	if len(trimmed) == 0 {
+		return 0
+	}
+	return trimmed[0]
+}
+
+func (object jsonObject) get(name string) json.RawMessage {
+	for _, member := range object {
+		if member.name == name {
+			return member.value
+		}
+	}
+	return nil
+}
+
+func (object jsonObject) stringValue(name string) *string {
+	var value string
+	if raw := object.get(name); raw == nil || json.Unmarshal(raw, &value) != nil {
+		return nil
+	}
+	return &value
+}
+
+func (object jsonObject) boolValue(name string) *bool {
+	var value bool
+	if raw := object.get(name); raw == nil || json.Unmarshal(raw, &value) != nil {
+		return nil
+	}
+	return &value
+}
+
+// Facets are numbers in CSDL JSON unless they're symbolic, e.g. "max" or "variable"
+func (object jsonObject) facetValue(name string) *string {
+	raw := object.get(name)
+	if raw == nil {
+		return nil
+	}
+	if value := object.stringValue(name); value != nil {
+		return value
+	}
+	value := string(bytes.TrimSpace(raw))
+	return &value
+}
+
+// Facets of properties, parameters and return types, which the model leaves to their unknown attributes
+var jsonFacets = []string{"$MaxLength", "$Precision", "$Scale", "$SRID", "$Unicode", "$DefaultValue"}
+
+// The members the model has no fields for, kept as the attributes CSDL XML writes them as, e.g. SRID="4326" for
+// "$SRID": 4326, in the order they come
+func (object jsonObject) unknownAttributes(names ...string) UnknownAttributes {
+	var attributes UnknownAttributes
+	for _, member := range object {
+		for _, name := range names {
+			if member.name == name {
+				attributes = append(attributes, xml.Attr{Name: xml.Name{Local: name[1:]}, Value: *object.facetValue(name)})
+			}
+		}
+	}
+	return attributes
+}
+
+func (object jsonObject) isTrue(name string) bool {
+	value := object.boolValue(name)
+	return value != nil && *value
+}
+
+// Nullable is left unset when it isn't stated, like in CSDL XML, so that both formats read into the same model
+func (object jsonObject) nullable() *bool {
+	return object.boolValue("$Nullable")
+}
+
+// The type of a property, parameter or return type. Strings are the default type in CSDL JSON.
+func (object jsonObject) typeName() string {
+	typeName := "Edm.String"
+	if value := object.stringValue("$Type"); value != nil {
+		typeName = *value
+	}
+	if object.isTrue("$Collection") {
+		typeName = fmt.Sprintf("Collection(%s)", typeName)
+	}
+	return typeName
+}
+
+// Members which are neither keywords ($) nor annotations (@) are the named elements, e.g. the properties of a type
+func isJSONElementName(name string) bool {
+	return !strings.HasPrefix(name, "$") && !strings.Contains(name, "@")
+}
+
+// Splits "@Org.OData.Core.V1.Description#Short" into its term and qualifier
+func parseJSONAnnotationName(name string) (string, *string) {
+	term := strings.TrimPrefix(name, "@")
+	if i := strings.Index(term, "#"); i >= 0 {
+		qualifier := term[i+1:]
+		return term[:i], &qualifier
+	}
+	return term, nil
+}
+
+// Terms of the standard vocabularies whose values are enum members, with the enum type of either the value itself or
+// the properties of its records, wherever they're nested
+var jsonEnumTerms = map[string]struct {
+	enumType   string
+	properties map[string]string
+}{
+	"Org.OData.Core.V1.Permissions":                    {enumType: "Permission"},
+	"Org.OData.Core.V1.Revisions":                      {properties: map[string]string{"Kind": "RevisionKind"}},
+	"Org.OData.Capabilities.V1.ConformanceLevel":       {enumType: "ConformanceLevelType"},
+	"Org.OData.Capabilities.V1.NavigationRestrictions": {properties: map[string]string{"Navigability": "NavigationType"}},
+	"Org.OData.Capabilities.V1.SearchRestrictions":     {properties: map[string]string{"UnsupportedExpressions": "SearchExpressions"}},
+}
+
+// Aliases the standard vocabularies usually go by
+var jsonVocabularyAliases = map[string]string{
+	"Core":         coreVocabulary,
+	"Capabilities": "Org.OData.Capabilities.V1",
+}
+
+// The members of a value of the enum type as CSDL XML writes them, e.g. "Core.Permission/Read Core.Permission/Write"
+// for "Read,Write"
+func jsonEnumMember(enumType string, value string) *string {
+	members := strings.Split(value, ",")
+	for i, member := range members {
+		members[i] = enumType + "/" + strings.TrimSpace(member)
+	}
+	enumMember := strings.Join(members, " ")
+	return &enumMember
+}
+
+func qualifyJSONRecordEnumMembers(expression *Expression, namespace string, properties map[string]string) {
+	records := []*Record{}
+	if expression.Record != nil {
+		records = append(records, expression.Record)
+	}
+	if expression.Collection != nil {
+		for i := range expression.Collection.Records {
+			records = append(records, &expression.Collection.Records[i])
+		}
+	}
+
+	for _, record := range records {
+		for i := range record.PropertyValues {
+			value := &record.PropertyValues[i]
+			if enumType, ok := properties[value.Property]; ok && value.String != nil {
+				value.EnumMember = jsonEnumMember(namespace+"."+enumType, *value.String)
+				value.String = nil
+				continue
+			}
+			qualifyJSONRecordEnumMembers(&value.Expression, namespace, properties)
+		}
+	}
+}
+
+// CSDL JSON renders enum members as bare names, so the ones of the terms of the standard vocabularies are qualified
+// with their enum type, in the namespace or alias the term is written with, to read the same as CSDL XML
+func qualifyJSONEnumMembers(term string, expression *Expression) {
+	i := strings.LastIndex(term, ".")
+	if i < 0 {
+		return
+	}
+	namespace := term[:i]
+	if vocabulary, ok := jsonVocabularyAliases[namespace]; ok {
+		namespace = vocabulary
+	}
+	enum, ok := jsonEnumTerms[namespace+term[i:]]
+	if !ok {
+		return
+	}
+
+	if enum.enumType != "" && expression.String != nil {
+		expression.EnumMember = jsonEnumMember(term[:i]+"."+enum.enumType, *expression.String)
+		expression.String = nil
+	}
+	qualifyJSONRecordEnumMembers(expression, term[:i], enum.properties)
+}
+
+// The annotations of the element named by the prefix, e.g. "Member@Core.Description" for the prefix "Member".
+// Annotations of annotations are skipped.
+func parseJSONAnnotations(object jsonObject, prefix string) ([]Annotation, error) {
+	annotations := []Annotation{}
+	for _, member := range object {
+		if !strings.HasPrefix(member.name, prefix+"@") || strings.Contains(member.name[len(prefix)+1:], "@") {
+			continue
+		}
+		term, qualifier := parseJSONAnnotationName(member.name[len(prefix):])
+		expression, err := parseJSONExpression(member.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of annotation '%s': %w", member.name, err)
+		}
+		qualifyJSONEnumMembers(term, &expression)
+		annotations = append(annotations, Annotation{Term: term, Qualifier: qualifier, Expression: expression})
+	}
+
+	if len(annotations) == 0 {
+		return nil, nil
+	}
+	return annotations, nil
+}
+
+func parseJSONPath(object jsonObject, expression *Expression) bool {
+	paths := map[string]**string{
+		"$Path":                   &expression.Path,
+		"$PropertyPath":           &expression.PropertyPath,
+		"$NavigationPropertyPath": &expression.NavigationPropertyPath,
+		"$AnnotationPath":         &expression.AnnotationPath,
+	}
+	for name, target := range paths {
+		if value := object.stringValue(name); value != nil {
+			*target = value
+			return true
+		}
+	}
+	return false
+}
+
+func parseJSONRecord(object jsonObject) (*Record, error) {
+	record := &Record{}
+	for _, name := range []string{"@type", "@odata.type"} {
+		if value := object.stringValue(name); value != nil {
+			record.Type = value
+		}
+	}
+
+	for _, member := range object {
+		if !isJSONElementName(member.name) {
+			continue
+		}
+		expression, err := parseJSONExpression(member.value)
+		if err != nil {
+			return nil, err
+		}
+		record.PropertyValues = append(record.PropertyValues, PropertyValue{Property: member.name, Expression: expression})
+	}
+
+	annotations, err := parseJSONAnnotations(object, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, annotation := range annotations {
+		if annotation.Term != "type" && annotation.Term != "odata.type" {
+			record.Annotations = append(record.Annotations, annotation)
+		}
+	}
+
+	return record, nil
+}
+
+func parseJSONCollection(data json.RawMessage) (*CollectionExpression, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+
+	collection := &CollectionExpression{}
+	for _, item := range items {
+		expression, err := parseJSONExpression(item)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case expression.String != nil:
+			collection.Strings = append(collection.Strings, *expression.String)
+		case expression.Int != nil:
+			collection.Ints = append(collection.Ints, *expression.Int)
+		case expression.Path != nil:
+			collection.Paths = append(collection.Paths, *expression.Path)
+		case expression.PropertyPath != nil:
+			collection.PropertyPaths = append(collection.PropertyPaths, *expression.PropertyPath)
+		case expression.NavigationPropertyPath != nil:
+			collection.NavigationPropertyPaths = append(collection.NavigationPropertyPaths, *expression.NavigationPropertyPath)
+		case expression.Record != nil:
+			collection.Records = append(collection.Records, *expression.Record)
+		}
+	}
+
+	return collection, nil
+}
+
+// Constant expressions are plain JSON values, while paths and records are objects. Enum members are rendered as strings.
+func parseJSONExpression(data json.RawMessage) (Expression, error) {
+	expression := Expression{}
+
+	switch jsonKind(data) {
+	case '{':
+		object, err := decodeJSONObject(data)
+		if err != nil {
+			return expression, err
+		}
+		if !parseJSONPath(object, &expression) {
+			expression.Record, err = parseJSONRecord(object)
+		}
+		return expression, err
+	case '[':
+		collection, err := parseJSONCollection(data)
+		expression.Collection = collection
+		return expression, err
+	case '"':
+		var value string
+		err := json.Unmarshal(data, &value)
+		expression.String = &value
+		return expression, err
+	case 't', 'f':
+		value := string(bytes.TrimSpace(data))
+		expression.Bool = &value
+	case 'n', 0:
+	default:
+		value := string(bytes.TrimSpace(data))
+		if strings.ContainsAny(value, ".eE") {
+			expression.Float = &value
+		} else {
+			expression.Int = &value
+		}
+	}
+
+	return expression, nil
+}
+
+func parseJSONProperties(object jsonObject) ([]Property, []NavigationProperty, error) {
+	properties := []Property{}
+	navProps := []NavigationProperty{}
+
+	for _, member := range object {
+		if !isJSONElementName(member.name) {
+			continue
+		}
+		element, err := decodeJSONObject(member.value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid property '%s': %w", member.name, err)
+		}
+		annotations, err := parseJSONAnnotations(element, "")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if kind := element.stringValue("$Kind"); kind != nil && *kind == "NavigationProperty" {
+			navProp := NavigationProperty{
+				Name:           member.name,
+				Type:           element.typeName(),
+				Partner:        element.stringValue("$Partner"),
+				ContainsTarget: element.isTrue("$ContainsTarget"),
+				Annotations:    annotations,
+			}
+			if !element.isTrue("$Collection") {
+				navProp.Nullable = element.nullable()
+			}
+			if raw := element.get("$ReferentialConstraint"); raw != nil {
+				constraints, err := decodeJSONObject(raw)
+				if err != nil {
+					return nil, nil, err
+				}
+				for _, constraint := range constraints {
+					if referenced := constraints.stringValue(constraint.name); isJSONElementName(constraint.name) && referenced != nil {
+						navProp.ReferentialConstraints = append(navProp.ReferentialConstraints, ReferentialConstraint{
+							Property:           constraint.name,
+							ReferencedProperty: *referenced,
+						})
+					}
+				}
+			}
+			navProps = append(navProps, navProp)
+			continue
+		}
+
+		properties = append(properties, Property{
+			Name:              member.name,
+			Type:              element.typeName(),
+			Nullable:          element.nullable(),
+			Annotations:       annotations,
+			UnknownAttributes: element.unknownAttributes(jsonFacets...),
+		})
+	}
+
+	return properties, navProps, nil
+}
+
+func parseJSONEntityType(name string, object jsonObject) (EntityType, error) {
+	properties, navProps, err := parseJSONProperties(object)
+	if err != nil {
+		return EntityType{}, err
+	}
+	annotations, err := parseJSONAnnotations(object, "")
+	if err != nil {
+		return EntityType{}, err
+	}
+
+	entityType := EntityType{
+		Name:                 name,
+		HasStream:            object.isTrue("$HasStream"),
+		BaseType:             object.stringValue("$BaseType"),
+		Abstract:             object.isTrue("$Abstract"),
+		OpenType:             object.isTrue("$OpenType"),
+		Properties:           properties,
+		NavigationProperties: navProps,
+		Annotations:          annotations,
+	}
+
+	// Key properties are either paths or objects mapping aliases to paths
+	if raw := object.get("$Key"); raw != nil {
+		var keyItems []json.RawMessage
+		if err := json.Unmarshal(raw, &keyItems); err != nil {
+			return EntityType{}, fmt.Errorf("invalid key of entity type '%s': %w", name, err)
+		}
+		key := []PropertyRef{}
+		for _, item := range keyItems {
+			var path string
+			if json.Unmarshal(item, &path) == nil {
+				key = append(key, PropertyRef{Name: path})
+			} else if aliased, err := decodeJSONObject(item); err == nil && len(aliased) > 0 {
+				if err := json.Unmarshal(aliased[0].value, &path); err != nil {
+					return EntityType{}, err
+				}
+				key = append(key, PropertyRef{Name: path})
+			}
+		}
+		entityType.Key = &key
+	}
+
+	return entityType, nil
+}
+
+func parseJSONComplexType(name string, object jsonObject) (ComplexType, error) {
+	properties, navProps, err := parseJSONProperties(object)
+	if err != nil {
+		return ComplexType{}, err
+	}
+	annotations, err := parseJSONAnnotations(object, "")
+	if err != nil {
+		return ComplexType{}, err
+	}
+
+	return ComplexType{
+		Name:                 name,
+		BaseType:             object.stringValue("$BaseType"),
+		Abstract:             object.isTrue("$Abstract"),
+		OpenType:             object.isTrue("$OpenType"),
+		Properties:           properties,
+		NavigationProperties: navProps,
+		Annotations:          annotations,
+	}, nil
+}
+
+func parseJSONEnumType(name string, object jsonObject) (EnumType, error) {
+	annotations, err := parseJSONAnnotations(object, "")
+	if err != nil {
+		return EnumType{}, err
+	}
+
+	enumType := EnumType{
+		Name:        name,
+		IsFlags:     object.isTrue("$IsFlags"),
+		Annotations: annotations,
+	}
+	if underlyingType := object.stringValue("$UnderlyingType"); underlyingType != nil {
+		enumType.UnderlyingType = *underlyingType
+	}
+
+	for _, member := range object {
+		if !isJSONElementName(member.name) {
+			continue
+		}
+		memberAnnotations, err := parseJSONAnnotations(object, member.name)
+		if err != nil {
+			return EnumType{}, err
+		}
+		enumType.Members = append(enumType.Members, EnumTypeMember{
+			Name:        member.name,
+			Value:       string(bytes.TrimSpace(member.value)),
+			Annotations: memberAnnotations,
+		})
+	}
+
+	return enumType, nil
+}
+
+func parseJSONTypeDefinition(name string, object jsonObject) (TypeDefinition, error) {
+	annotations, err := parseJSONAnnotations(object, "")
+	if err != nil {
+		return TypeDefinition{}, err
+	}
+
+	typeDefinition := TypeDefinition{
+		Name:        name,
+		MaxLength:   object.facetValue("$MaxLength"),
+		Precision:   object.facetValue("$Precision"),
+		Scale:       object.facetValue("$Scale"),
+		SRID:        object.facetValue("$SRID"),
+		Unicode:     object.boolValue("$Unicode"),
+		Annotations: annotations,
+	}
+	if underlyingType := object.stringValue("$UnderlyingType"); underlyingType != nil {
+		typeDefinition.UnderlyingType = *underlyingType
+	}
+
+	return typeDefinition, nil
+}
+
+func parseJSONParameters(object jsonObject) ([]Parameter, error) {
+	raw := object.get("$Parameter")
+	if raw == nil {
+		return nil, nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+
+	parameters := []Parameter{}
+	for _, item := range items {
+		element, err := decodeJSONObject(item)
+		if err != nil {
+			return nil, err
+		}
+		annotations, err := parseJSONAnnotations(element, "")
+		if err != nil {
+			return nil, err
+		}
+		parameter := Parameter{
+			Type:              element.typeName(),
+			Nullable:          element.nullable(),
+			Annotations:       annotations,
+			UnknownAttributes: element.unknownAttributes(jsonFacets...),
+		}
+		if name := element.stringValue("$Name"); name != nil {
+			parameter.Name = *name
+		}
+		parameters = append(parameters, parameter)
+	}
+
+	return parameters, nil
+}
+
+func parseJSONReturnType(object jsonObject) (*ReturnType, error) {
+	raw := object.get("$ReturnType")
+	if raw == nil {
+		return nil, nil
+	}
+
+	element, err := decodeJSONObject(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &ReturnType{Type: element.typeName(), Nullable: element.nullable(), UnknownAttributes: element.unknownAttributes(jsonFacets...)}, nil
+}
+
+// Adds the overloads of a function or an action to the schema
+func parseJSONOperations(name string, data json.RawMessage, schema *Schema) error {
+	var overloads []json.RawMessage
+	if err := json.Unmarshal(data, &overloads); err != nil {
+		return fmt.Errorf("invalid operation '%s': %w", name, err)
+	}
+
+	for _, overload := range overloads {
+		object, err := decodeJSONObject(overload)
+		if err != nil {
+			return fmt.Errorf("invalid operation '%s': %w", name, err)
+		}
+		parameters, err := parseJSONParameters(object)
+		if err != nil {
+			return fmt.Errorf("invalid parameters of operation '%s': %w", name, err)
+		}
+		returnType, err := parseJSONReturnType(object)
+		if err != nil {
+			return fmt.Errorf("invalid return type of operation '%s': %w", name, err)
+		}
+		annotations, err := parseJSONAnnotations(object, "")
+		if err != nil {
+			return err
+		}
+
+		switch kind := object.stringValue("$Kind"); {
+		case kind != nil && *kind == "Function":
+			function := Function{
+				Name:          name,
+				IsBound:       object.isTrue("$IsBound"),
+				EntitySetPath: object.stringValue("$EntitySetPath"),
+				IsComposable:  object.isTrue("$IsComposable"),
+				Parameters:    parameters,
+				Annotations:   annotations,
+			}
+			if returnType != nil {
+				function.ReturnType = *returnType
+			}
+			schema.Functions = append(schema.Functions, function)
+		case kind != nil && *kind == "Action":
+			schema.Actions = append(schema.Actions, Action{
+				Name:          name,
+				IsBound:       object.isTrue("$IsBound"),
+				EntitySetPath: object.stringValue("$EntitySetPath"),
+				Parameters:    parameters,
+				ReturnType:    returnType,
+				Annotations:   annotations,
+			})
+		default:
+			return fmt.Errorf("unknown kind of operation '%s'", name)
+		}
+	}
+
+	return nil
+}
+
+func parseJSONBindings(object jsonObject) ([]NavigationPropertyBinding, error) {
+	raw := object.get("$NavigationPropertyBinding")
+	if raw == nil {
+		return nil, nil
+	}
+
+	bindings, err := decodeJSONObject(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []NavigationPropertyBinding{}
+	for _, binding := range bindings {
+		if target := bindings.stringValue(binding.name); target != nil {
+			result = append(result, NavigationPropertyBinding{Path: binding.name, Target: *target})
+		}
+	}
+	return result, nil
+}
+
+// Entity sets are collections of entities, singletons single ones, and the imports name the operation they import
+func parseJSONEntityContainer(name string, object jsonObject) (*EntityContainer, error) {
+	annotations, err := parseJSONAnnotations(object, "")
+	if err != nil {
+		return nil, err
+	}
+	container := &EntityContainer{Name: name, Extends: object.stringValue("$Extends"), Annotations: annotations}
+
+	for _, member := range object {
+		if !isJSONElementName(member.name) {
+			continue
+		}
+		element, err := decodeJSONObject(member.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid container element '%s': %w", member.name, err)
+		}
+		elementAnnotations, err := parseJSONAnnotations(element, "")
+		if err != nil {
+			return nil, err
+		}
+		bindings, err := parseJSONBindings(element)
+		if err != nil {
+			return nil, err
+		}
+		entitySet := ""
+		if value := element.stringValue("$EntitySet"); value != nil {
+			entitySet = *value
+		}
+
+		switch {
+		case element.stringValue("$Function") != nil:
+			container.FunctionImports = append(container.FunctionImports, FunctionImport{
+				Name:              member.name,
+				Function:          *element.stringValue("$Function"),
+				EntitySet:         entitySet,
+				Annotations:       elementAnnotations,
+				UnknownAttributes: element.unknownAttributes("$IncludeInServiceDocument"),
+			})
+		case element.stringValue("$Action") != nil:
+			container.ActionImports = append(container.ActionImports, ActionImport{
+				Name:        member.name,
+				Action:      *element.stringValue("$Action"),
+				EntitySet:   entitySet,
+				Annotations: elementAnnotations,
+			})
+		case element.stringValue("$Type") == nil:
+			return nil, fmt.Errorf("container element '%s' has no type", member.name)
+		case element.isTrue("$Collection"):
+			container.EntitySets = append(container.EntitySets, EntitySet{
+				Name:                       member.name,
+				EntityType:                 *element.stringValue("$Type"),
+				NavigationPropertyBindings: bindings,
+				Annotations:                elementAnnotations,
+				UnknownAttributes:          element.unknownAttributes("$IncludeInServiceDocument"),
+			})
+		default:
+			container.Singletons = append(container.Singletons, Singleton{
+				Name:                       member.name,
+				Type:                       *element.stringValue("$Type"),
+				NavigationPropertyBindings: bindings,
+				Annotations:                elementAnnotations,
+			})
+		}
+	}
+
+	return container, nil
+}
+
+func parseJSONExternalAnnotations(data json.RawMessage) ([]Annotations, error) {
+	targets, err := decodeJSONObject(data)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Annotations{}
+	for _, target := range targets {
+		object, err := decodeJSONObject(target.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid annotations of target '%s': %w", target.name, err)
+		}
+		annotations, err := parseJSONAnnotations(object, "")
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, Annotations{Target: target.name, Annotations: annotations})
+	}
+	return result, nil
+}
+
+func parseJSONSchema(namespace string, data json.RawMessage) (Schema, error) {
+	object, err := decodeJSONObject(data)
+	if err != nil {
+		return Schema{}, fmt.Errorf("invalid schema '%s': %w", namespace, err)
+	}
+
+	schema := Schema{
+		Namespace: namespace,
+		Alias:     object.stringValue("$Alias"),
+	}
+	if schema.Annotations, err = parseJSONAnnotations(object, ""); err != nil {
+		return Schema{}, err
+	}
+	if raw := object.get("$Annotations"); raw != nil {
+		if schema.ExternalAnnotations, err = parseJSONExternalAnnotations(raw); err != nil {
+			return Schema{}, err
+		}
+	}
+
+	for _, member := range object {
+		if !isJSONElementName(member.name) {
+			continue
+		}
+		if jsonKind(member.value) == '[' {
+			if err := parseJSONOperations(member.name, member.value, &schema); err != nil {
+				return Schema{}, err
+			}
+			continue
+		}
+
+		element, err := decodeJSONObject(member.value)
+		if err != nil {
+			return Schema{}, fmt.Errorf("invalid schema element '%s': %w", member.name, err)
+		}
+		kind := element.stringValue("$Kind")
+		if kind == nil {
+			continue
+		}
+
+		switch *kind {
+		case "EntityType":
+			entityType, err := parseJSONEntityType(member.name, element)
+			if err != nil {
+				return Schema{}, err
+			}
+			schema.EntityTypes = append(schema.EntityTypes, entityType)
+		case "ComplexType":
+			complexType, err := parseJSONComplexType(member.name, element)
+			if err != nil {
+				return Schema{}, err
+			}
+			schema.ComplexTypes = append(schema.ComplexTypes, complexType)
+		case "EnumType":
+			enumType, err := parseJSONEnumType(member.name, element)
+			if err != nil {
+				return Schema{}, err
+			}
+			schema.EnumTypes = append(schema.EnumTypes, enumType)
+		case "TypeDefinition":
+			typeDefinition, err := parseJSONTypeDefinition(member.name, element)
+			if err != nil {
+				return Schema{}, err
+			}
+			schema.TypeDefinitions = append(schema.TypeDefinitions, typeDefinition)
+		case "EntityContainer":
+			container, err := parseJSONEntityContainer(member.name, element)
+			if err != nil {
+				return Schema{}, err
+			}
+			schema.EntityContainers = append(schema.EntityContainers, *container)
+		}
+	}
+
+	return schema, nil
+}
+
+func parseJSONReferences(data json.RawMessage) ([]Reference, error) {
+	uris, err := decodeJSONObject(data)
+	if err != nil {
+		return nil, err
+	}
+
+	references := []Reference{}
+	for _, uri := range uris {
+		var reference struct {
+			Includes []struct {
+				Namespace string  `json:"$Namespace"`
+				Alias     *string `json:"$Alias"`
+			} `json:"$Include"`
+			IncludeAnnotations []struct {
+				TermNamespace   string  `json:"$TermNamespace"`
+				Qualifier       *string `json:"$Qualifier"`
+				TargetNamespace *string `json:"$TargetNamespace"`
+			} `json:"$IncludeAnnotations"`
+		}
+		if err := json.Unmarshal(uri.value, &reference); err != nil {
+			return nil, fmt.Errorf("invalid reference '%s': %w", uri.name, err)
+		}
+
+		result := Reference{Uri: uri.name}
+		for _, include := range reference.Includes {
+			result.Includes = append(result.Includes, Include{Namespace: include.Namespace, Alias: include.Alias})
+		}
+		for _, include := range reference.IncludeAnnotations {
+			result.IncludeAnnotations = append(result.IncludeAnnotations, IncludeAnnotations{
+				TermNamespace:   include.TermNamespace,
+				Qualifier:       include.Qualifier,
+				TargetNamespace: include.TargetNamespace,
+			})
+		}
+		references = append(references, result)
+	}
+	return references, nil
+}
+
+// The root names the container of the service, e.g. "Trippin.Container"
+func resolveJSONEntityContainer(edm *EdmxDocument, qualifiedName string) error {
+	for i := range edm.DataServices.Schemas {
+		schema := &edm.DataServices.Schemas[i]
+		for j := range schema.EntityContainers {
+			name := schema.EntityContainers[j].Name
+			if qualifiedName == fmt.Sprintf("%s.%s", schema.Namespace, name) || (schema.Alias != nil && qualifiedName == fmt.Sprintf("%s.%s", *schema.Alias, name)) {
+				schema.EntityContainers[j].IsDefault = true
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("entity container '%s' of the service is not declared", qualifiedName)
+}
+
+// Reads a CSDL JSON document into the same model as the CSDL XML one
+func ParseJSON(data []byte) (*EdmxDocument, error) {
+	root, err := decodeJSONObject(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSDL JSON document: %w", err)
+	}
+
+	edm := &EdmxDocument{Version: "4.0"}
+	if version := root.stringValue("$Version"); version != nil {
+		edm.Version = *version
+	}
+	if raw := root.get("$Reference"); raw != nil {
+		if edm.References, err = parseJSONReferences(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, member := range root {
+		if !isJSONElementName(member.name) {
+			continue
+		}
+		schema, err := parseJSONSchema(member.name, member.value)
+		if err != nil {
+			return nil, err
+		}
+		edm.DataServices.Schemas = append(edm.DataServices.Schemas, schema)
+	}
+	if name := root.stringValue("$EntityContainer"); name != nil {
+		if err := resolveJSONEntityContainer(edm, *name); err != nil {
+			return nil, err
+		}
+	}
+	markDefaultEntityContainer(edm.DataServices.Schemas)
+
+	return edm, nil
+}
@@ -0,0 +1,99 @@
+package odataschema
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Clears what only the XML decoder records, i.e. the names of the elements and their positions, and empty lists.
+// Constant expressions written as elements, e.g. <String>, are taken as their attribute forms, which CSDL JSON reads into.
+func normalizeModel(value reflect.Value) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if !value.IsNil() {
+			normalizeModel(value.Elem())
+		}
+	case reflect.Slice:
+		if value.Len() == 0 {
+			value.Set(reflect.Zero(value.Type()))
+		}
+		for i := 0; i < value.Len(); i++ {
+			normalizeModel(value.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			name := value.Type().Field(i).Name
+			if attribute := value.FieldByName(strings.TrimSuffix(name, "Element")); strings.HasSuffix(name, "Element") && attribute.IsValid() {
+				if attribute.IsNil() {
+					attribute.Set(value.Field(i))
+				}
+				value.Field(i).Set(reflect.Zero(value.Field(i).Type()))
+			}
+			switch name {
+			case "XMLName", "Position":
+				value.Field(i).Set(reflect.Zero(value.Field(i).Type()))
+			default:
+				normalizeModel(value.Field(i))
+			}
+		}
+	}
+}
+
+func parseTestFile(t *testing.T, path string) *EdmxDocument {
+	t.Helper()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edm, err := ParseBytes(data)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	normalizeModel(reflect.ValueOf(edm))
+	return edm
+}
+
+func TestParseJSONConformsToXML(t *testing.T) {
+	for _, test := range []struct {
+		xmlPath  string
+		jsonPath string
+	}{
+		{"testdata/conformance.xml", "testdata/conformance.json"},
+		{"../schemas/trippin.xml", "testdata/trippin.json"},
+	} {
+		t.Run(test.jsonPath, func(t *testing.T) {
+			fromXML := parseTestFile(t, test.xmlPath)
+			fromJSON := parseTestFile(t, test.jsonPath)
+
+			if !reflect.DeepEqual(fromXML, fromJSON) {
+				xmlModel, _ := json.MarshalIndent(fromXML, "", "  ")
+				jsonModel, _ := json.MarshalIndent(fromJSON, "", "  ")
+				t.Errorf("the models differ\nXML:\n%s\nJSON:\n%s", xmlModel, jsonModel)
+			}
+		})
+	}
+}
+
+func TestParseJSONEntityContainer(t *testing.T) {
+	data := []byte(`{
+		"$Version": "4.0",
+		"$EntityContainer": "S.Container",
+		"First": {"Container": {"$Kind": "EntityContainer"}},
+		"Second": {"$Alias": "S", "Container": {"$Kind": "EntityContainer", "$Extends": "First.Container"}}
+	}`)
+	edm, err := ParseJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemas := edm.DataServices.Schemas
+	if schemas[0].EntityContainers[0].IsDefault || !schemas[1].EntityContainers[0].IsDefault {
+		t.Errorf("expected only the container of the second schema to be the default one")
+	}
+
+	if _, err := ParseJSON([]byte(`{"$EntityContainer": "Missing.Container", "First": {"Container": {"$Kind": "EntityContainer"}}}`)); err == nil {
+		t.Errorf("expected an error for an undeclared container")
+	}
+}
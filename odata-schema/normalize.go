@@ -0,0 +1,344 @@
+package odataschema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kinvey/odata-schema/utils"
+)
+
+// Primitive types of CSDL v2/v3 which CSDL v4 replaced
+var legacyPrimitiveTypes = map[string]string{
+	"Edm.DateTime": "Edm.DateTimeOffset",
+	"Edm.Time":     "Edm.Duration",
+}
+
+// The OData version of the document, e.g. "4.0". CSDL v2/v3 documents carry it on DataServices, as Edmx is always "1.0" there.
+func (edm *EdmxDocument) ODataVersion() string {
+	if strings.HasPrefix(edm.Version, "4.") {
+		return edm.Version
+	}
+	if edm.DataServices.DataServiceVersion != "" {
+		return edm.DataServices.DataServiceVersion
+	}
+	return "1.0"
+}
+
+func (edm *EdmxDocument) IsLegacy() bool {
+	return !strings.HasPrefix(edm.ODataVersion(), "4.")
+}
+
+func qualifiedNames(schema *Schema, name string) []string {
+	names := []string{fmt.Sprintf("%s.%s", schema.Namespace, name)}
+	if schema.Alias != nil {
+		names = append(names, fmt.Sprintf("%s.%s", *schema.Alias, name))
+	}
+	return names
+}
+
+func normalizeTypeName(typeName string) string {
+	if strings.HasPrefix(typeName, "Collection(") && strings.HasSuffix(typeName, ")") {
+		return fmt.Sprintf("Collection(%s)", normalizeTypeName(typeName[len("Collection("):len(typeName)-1]))
+	}
+	if replacement, found := legacyPrimitiveTypes[typeName]; found {
+		return replacement
+	}
+	return typeName
+}
+
+func normalizeProperty(property *Property) {
+	// CSDL v3 declares collections as Type="Collection" with the item type in a TypeRef
+	if property.Type == "Collection" && property.TypeRef != nil {
+		property.Type = fmt.Sprintf("Collection(%s)", property.TypeRef.Type)
+	}
+	property.Type = normalizeTypeName(property.Type)
+}
+
+func normalizeParameters(parameters []Parameter) {
+	for i := range parameters {
+		parameters[i].Type = normalizeTypeName(parameters[i].Type)
+	}
+}
+
+func findAssociationEnd(association *Association, role string) *AssociationEnd {
+	for i := range association.Ends {
+		if association.Ends[i].Role == role {
+			return &association.Ends[i]
+		}
+	}
+	return nil
+}
+
+// Turns the referential constraint of the association into the one of the navigation property on the dependent end
+func navigationConstraints(association *Association, fromRole string) []ReferentialConstraint {
+	constraint := association.ReferentialConstraint
+	if constraint == nil || constraint.Dependent.Role != fromRole {
+		return nil
+	}
+
+	constraints := []ReferentialConstraint{}
+	for i, dependentRef := range constraint.Dependent.PropertyRefs {
+		if i < len(constraint.Principal.PropertyRefs) {
+			constraints = append(constraints, ReferentialConstraint{
+				Property:           dependentRef.Name,
+				ReferencedProperty: constraint.Principal.PropertyRefs[i].Name,
+			})
+		}
+	}
+	return constraints
+}
+
+// Types the navigation property after the end of its association it leads to, e.g. "Collection(NorthwindModel.Order)" for "*"
+func normalizeNavigationProperty(navProp *NavigationProperty, associations map[string]*Association) {
+	if navProp.Relationship == nil || navProp.Type != "" {
+		return
+	}
+
+	association, ok := associations[*navProp.Relationship]
+	if !ok {
+		return
+	}
+
+	toEnd := findAssociationEnd(association, navProp.ToRole)
+	if toEnd == nil {
+		return
+	}
+
+	navProp.Type = toEnd.Type
+	switch toEnd.Multiplicity {
+	case "*":
+		navProp.Type = fmt.Sprintf("Collection(%s)", toEnd.Type)
+	case "1":
+		nullable := false
+		navProp.Nullable = &nullable
+	}
+
+	if len(navProp.ReferentialConstraints) == 0 {
+		navProp.ReferentialConstraints = navigationConstraints(association, navProp.FromRole)
+	}
+}
+
+// Finds the navigation property on the other end of the association, which v4 calls the partner
+func findPartner(navProp *NavigationProperty, schemas []Schema, associations map[string]*Association) *string {
+	association, ok := associations[*navProp.Relationship]
+	if !ok {
+		return nil
+	}
+
+	for _, schema := range schemas {
+		for _, entityType := range schema.EntityTypes {
+			for _, candidate := range entityType.NavigationProperties {
+				if candidate.Relationship == nil || candidate.FromRole != navProp.ToRole || candidate.ToRole != navProp.FromRole {
+					continue
+				}
+				if associations[*candidate.Relationship] == association {
+					name := candidate.Name
+					return &name
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Binds the navigation properties of the entity sets at one end of the association set to the entity set at the other end
+// Marks the container of the service, unless the document declares several without marking any of them
+func markDefaultEntityContainer(schemas []Schema) {
+	containers := []*EntityContainer{}
+	for i := range schemas {
+		for j := range schemas[i].EntityContainers {
+			container := &schemas[i].EntityContainers[j]
+			for _, attr := range container.UnknownAttributes {
+				if attr.Name.Local == "IsDefaultEntityContainer" && attr.Value == "true" {
+					container.IsDefault = true
+				}
+			}
+			containers = append(containers, container)
+		}
+	}
+	if len(containers) == 1 {
+		containers[0].IsDefault = true
+	}
+}
+
+func bindAssociationSet(container *EntityContainer, associationSet AssociationSet, schemas []Schema, associations map[string]*Association) {
+	association, ok := associations[associationSet.Association]
+	if !ok {
+		return
+	}
+
+	setsByRole := make(map[string]string)
+	for _, end := range associationSet.Ends {
+		setsByRole[end.Role] = end.EntitySet
+	}
+
+	for _, schema := range schemas {
+		for _, entityType := range schema.EntityTypes {
+			for _, navProp := range entityType.NavigationProperties {
+				if navProp.Relationship == nil || associations[*navProp.Relationship] != association {
+					continue
+				}
+
+				for i := range container.EntitySets {
+					entitySet := &container.EntitySets[i]
+					if entitySet.Name != setsByRole[navProp.FromRole] || setsByRole[navProp.ToRole] == "" {
+						continue
+					}
+
+					// Navigation properties of derived types are bound with a type cast segment
+					path := navProp.Name
+					if !utils.SliceContainsString(qualifiedNames(&schema, entityType.Name), entitySet.EntityType) {
+						path = fmt.Sprintf("%s.%s/%s", schema.Namespace, entityType.Name, navProp.Name)
+					}
+
+					// The binding is already there when a written document is read again
+					if hasBinding(entitySet.NavigationPropertyBindings, path) {
+						continue
+					}
+					entitySet.NavigationPropertyBindings = append(entitySet.NavigationPropertyBindings, NavigationPropertyBinding{
+						Path:   path,
+						Target: setsByRole[navProp.ToRole],
+					})
+				}
+			}
+		}
+	}
+}
+
+func hasBinding(bindings []NavigationPropertyBinding, path string) bool {
+	for _, binding := range bindings {
+		if binding.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Service operations are side-effecting unless they are invoked with GET or declared otherwise
+func isLegacyAction(functionImport *FunctionImport) bool {
+	if functionImport.HttpMethod != nil {
+		return strings.ToUpper(*functionImport.HttpMethod) != "GET"
+	}
+	if functionImport.IsSideEffecting != nil {
+		return *functionImport.IsSideEffecting
+	}
+	return true
+}
+
+// Declares the operations of the v2/v3 function imports in the schema of the container, the way v4 does.
+// Bindable operations aren't imported in v4, so their imports are dropped.
+func normalizeFunctionImports(schema *Schema, container *EntityContainer) {
+	functionImports := []FunctionImport{}
+
+	for _, functionImport := range container.FunctionImports {
+		if functionImport.Function != "" {
+			functionImports = append(functionImports, functionImport)
+			continue
+		}
+
+		normalizeParameters(functionImport.Parameters)
+		var returnType *ReturnType
+		if functionImport.ReturnType != nil {
+			returnType = &ReturnType{Type: normalizeTypeName(*functionImport.ReturnType)}
+		}
+		qualifiedName := fmt.Sprintf("%s.%s", schema.Namespace, functionImport.Name)
+
+		if isLegacyAction(&functionImport) {
+			schema.Actions = append(schema.Actions, Action{
+				Name:          functionImport.Name,
+				IsBound:       functionImport.IsBindable,
+				Parameters:    functionImport.Parameters,
+				ReturnType:    returnType,
+				Annotations:   functionImport.Annotations,
+				Documentation: functionImport.Documentation,
+			})
+			if !functionImport.IsBindable {
+				container.ActionImports = append(container.ActionImports, ActionImport{
+					Name:      functionImport.Name,
+					EntitySet: functionImport.EntitySet,
+					Action:    qualifiedName,
+				})
+			}
+			continue
+		}
+
+		function := Function{
+			Name:          functionImport.Name,
+			IsBound:       functionImport.IsBindable,
+			Parameters:    functionImport.Parameters,
+			Annotations:   functionImport.Annotations,
+			Documentation: functionImport.Documentation,
+		}
+		if returnType != nil {
+			function.ReturnType = *returnType
+		}
+		schema.Functions = append(schema.Functions, function)
+		if !functionImport.IsBindable {
+			functionImport.Function = qualifiedName
+			functionImports = append(functionImports, functionImport)
+		}
+	}
+
+	container.FunctionImports = functionImports
+}
+
+// Rewrites the constructs of CSDL v2/v3 into their v4 counterparts, so that the rest of the tooling only deals with v4.
+// Streams (m:HasStream) need no rewriting, as they're read regardless of their namespace.
+// The container of the service is marked whatever the version.
+func normalize(edm *EdmxDocument) {
+	markDefaultEntityContainer(edm.DataServices.Schemas)
+	if !edm.IsLegacy() {
+		return
+	}
+
+	schemas := edm.DataServices.Schemas
+	associations := make(map[string]*Association)
+	for i := range schemas {
+		for j := range schemas[i].Associations {
+			for _, name := range qualifiedNames(&schemas[i], schemas[i].Associations[j].Name) {
+				associations[name] = &schemas[i].Associations[j]
+			}
+		}
+	}
+
+	for i := range schemas {
+		schema := &schemas[i]
+		for j := range schema.EntityTypes {
+			entityType := &schema.EntityTypes[j]
+			for k := range entityType.Properties {
+				normalizeProperty(&entityType.Properties[k])
+			}
+			for k := range entityType.NavigationProperties {
+				normalizeNavigationProperty(&entityType.NavigationProperties[k], associations)
+			}
+		}
+		for j := range schema.ComplexTypes {
+			for k := range schema.ComplexTypes[j].Properties {
+				normalizeProperty(&schema.ComplexTypes[j].Properties[k])
+			}
+		}
+	}
+
+	// Partners are looked up once all navigation properties are typed
+	for i := range schemas {
+		for j := range schemas[i].EntityTypes {
+			entityType := &schemas[i].EntityTypes[j]
+			for k := range entityType.NavigationProperties {
+				navProp := &entityType.NavigationProperties[k]
+				if navProp.Relationship != nil && navProp.Partner == nil {
+					navProp.Partner = findPartner(navProp, schemas, associations)
+				}
+			}
+		}
+	}
+
+	for i := range schemas {
+		for j := range schemas[i].EntityContainers {
+			container := &schemas[i].EntityContainers[j]
+			for _, associationSet := range container.AssociationSets {
+				bindAssociationSet(container, associationSet, schemas, associations)
+			}
+			normalizeFunctionImports(&schemas[i], container)
+		}
+	}
+}
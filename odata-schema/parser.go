@@ -1,27 +1,57 @@
 package odataschema
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/xml"
 	"io/ioutil"
 	"os"
 )
 
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// Streams CSDL XML files, as they may be large. CSDL JSON ones are read whole.
 func Parse(filePath string) (*EdmxDocument, error) {
-	xmlFile, err := os.Open(filePath)
+	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
 
-	defer xmlFile.Close()
+	defer file.Close()
 
-	bytes, err := ioutil.ReadAll(xmlFile)
+	reader := bufio.NewReader(file)
+	start, _ := reader.Peek(512)
+	if trimmed := bytes.TrimSpace(bytes.TrimPrefix(start, utf8BOM)); len(trimmed) > 0 && trimmed[0] == '{' {
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		return ParseBytes(data)
+	}
 
-	if err != nil {
-		return nil, err
+	return ParseReader(reader, ParseOptions{FileName: filePath})
+}
+
+// Reads either CSDL XML or CSDL JSON, telling them apart by the first character of the document
+func ParseBytes(data []byte) (*EdmxDocument, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, utf8BOM))
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return ParseJSON(trimmed)
 	}
+	return ParseXML(data)
+}
 
-	var edm EdmxDocument
-	xml.Unmarshal(bytes, &edm)
+func ParseXML(data []byte) (*EdmxDocument, error) {
+	return ParseReader(bytes.NewReader(data), ParseOptions{})
+}
 
+// Decodes the whole document at once, which yields the same model as ParseXML less the positions of the elements.
+// The streaming decoder is measured and checked against it.
+func unmarshalXML(data []byte) (*EdmxDocument, error) {
+	var edm EdmxDocument
+	if err := xml.Unmarshal(data, &edm); err != nil {
+		return nil, err
+	}
+	normalize(&edm)
 	return &edm, nil
 }
@@ -0,0 +1,145 @@
+package odataschema
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type schemaFile struct {
+	name string
+	data []byte
+}
+
+func readSchemaFiles(tb testing.TB) []schemaFile {
+	tb.Helper()
+	paths, err := filepath.Glob("../schemas/*.xml")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	files := []schemaFile{}
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		files = append(files, schemaFile{name: filepath.Base(path), data: data})
+	}
+	return files
+}
+
+func TestParseXMLMatchesUnmarshal(t *testing.T) {
+	for _, file := range readSchemaFiles(t) {
+		t.Run(file.name, func(t *testing.T) {
+			streamed, err := ParseXML(file.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			unmarshaled, err := unmarshalXML(file.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			normalizeModel(reflect.ValueOf(streamed))
+			normalizeModel(reflect.ValueOf(unmarshaled))
+			if !reflect.DeepEqual(streamed, unmarshaled) {
+				t.Errorf("the streamed model differs from the unmarshaled one")
+			}
+		})
+	}
+}
+
+func BenchmarkParseXML(b *testing.B) {
+	for _, file := range readSchemaFiles(b) {
+		b.Run(file.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(file.data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseXML(file.data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	for _, file := range readSchemaFiles(b) {
+		b.Run(file.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(file.data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := unmarshalXML(file.data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseXMLKeepsEveryEntityContainer(t *testing.T) {
+	data := []byte(`<edmx:Edmx Version="1.0" xmlns:edmx="http://schemas.microsoft.com/ado/2007/06/edmx">
+  <edmx:DataServices>
+    <Schema Namespace="Multi" xmlns="http://schemas.microsoft.com/ado/2009/11/edm" xmlns:m="http://schemas.microsoft.com/ado/2007/08/dataservices/metadata">
+      <EntityContainer Name="Base" />
+      <EntityContainer Name="Service" Extends="Multi.Base" m:IsDefaultEntityContainer="true" />
+    </Schema>
+  </edmx:DataServices>
+</edmx:Edmx>`)
+
+	for name, parse := range map[string]func([]byte) (*EdmxDocument, error){"ParseXML": ParseXML, "xml.Unmarshal": unmarshalXML} {
+		edm, err := parse(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		containers := edm.DataServices.Schemas[0].EntityContainers
+		if len(containers) != 2 || containers[0].Name != "Base" || containers[1].Name != "Service" {
+			t.Errorf("%s: expected the containers 'Base' and 'Service', got %v", name, containers)
+		} else if containers[0].IsDefault || !containers[1].IsDefault {
+			t.Errorf("%s: expected only the container 'Service' to be the default one", name)
+		}
+	}
+}
+
+func TestParseReaderSetsPositions(t *testing.T) {
+	data := `<edmx:Edmx Version="4.0" xmlns:edmx="http://docs.oasis-open.org/odata/ns/edmx">
+  <edmx:DataServices>
+    <Schema Namespace="S" xmlns="http://docs.oasis-open.org/odata/ns/edm">
+      <EntityType Name="Person">
+        <Key><PropertyRef Name="ID" /></Key>
+        <Annotation Term="Core.Description" String="Someone" />
+        <Property Name="ID" Type="Edm.Int32" Nullable="false" />
+        <NavigationProperty Name="Friends" Type="Collection(S.Person)" />
+        <Property Name="Name" Type="Edm.String" />
+      </EntityType>
+      <Function Name="Oldest"><ReturnType Type="S.Person" /></Function>
+    </Schema>
+  </edmx:DataServices>
+</edmx:Edmx>`
+
+	edm, err := ParseReader(strings.NewReader(data), ParseOptions{FileName: "people.xml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := edm.DataServices.Schemas[0]
+	person := schema.EntityTypes[0]
+	for _, test := range []struct {
+		element  string
+		position Position
+		expected string
+	}{
+		{"Edmx", edm.Position, "people.xml:1:1"},
+		{"Schema", schema.Position, "people.xml:3:5"},
+		{"Person", person.Position, "people.xml:4:7"},
+		{"ID", person.Properties[0].Position, "people.xml:7:9"},
+		{"Friends", person.NavigationProperties[0].Position, "people.xml:8:9"},
+		{"Name", person.Properties[1].Position, "people.xml:9:9"},
+		{"Oldest", schema.Functions[0].Position, "people.xml:11:7"},
+		{"ReturnType", schema.Functions[0].ReturnType.Position, "people.xml:11:31"},
+	} {
+		if test.position.String() != test.expected {
+			t.Errorf("%s: expected the position %s, got %s", test.element, test.expected, test.position)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package odataschema
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type ParseOptions struct {
+	// Name of the file the document is read from, which the positions of the elements refer to
+	FileName string
+	// Local names of the elements to leave out wherever they appear, e.g. "Annotation" or "Documentation"
+	SkipElements []string
+}
+
+// Reader which keeps track of where the lines of the document start, up to the offsets positions were asked for.
+// It reads a byte at a time like the decoder does, so that only the lines of the token at hand are held.
+type lineCounter struct {
+	reader     io.ByteReader
+	read       int64
+	lineStarts []int64
+	line       int
+	lineStart  int64
+}
+
+func (counter *lineCounter) ReadByte() (byte, error) {
+	b, err := counter.reader.ReadByte()
+	if err != nil {
+		return b, err
+	}
+	counter.read++
+	if b == '\n' {
+		counter.lineStarts = append(counter.lineStarts, counter.read)
+	}
+	return b, nil
+}
+
+func (counter *lineCounter) Read(p []byte) (int, error) {
+	for i := range p {
+		b, err := counter.ReadByte()
+		if err != nil {
+			return i, err
+		}
+		p[i] = b
+	}
+	return len(p), nil
+}
+
+// The line and column of the offset, which mustn't be less than the one asked for before
+func (counter *lineCounter) position(offset int64) (int, int) {
+	passed := 0
+	for passed < len(counter.lineStarts) && counter.lineStarts[passed] <= offset {
+		counter.line++
+		counter.lineStart = counter.lineStarts[passed]
+		passed++
+	}
+	counter.lineStarts = counter.lineStarts[:copy(counter.lineStarts, counter.lineStarts[passed:])]
+	return counter.line + 1, int(offset-counter.lineStart) + 1
+}
+
+// Reader which keeps track of where the elements start and drops the skipped elements along with their content before
+// they're decoded. Namespaces are left to the decoder reading from it, so raw tokens are passed on as they are.
+type sourceReader struct {
+	decoder *xml.Decoder
+	lines   lineCounter
+	file    string
+	skip    map[string]bool
+	depth   int
+	// Starts of the elements read since the decoder loop last took them, which it does once it has decoded an element
+	starts []elementStart
+	// Where the starts are held unless there are more at once than it fits, so that it comes along with the reader
+	startsBuffer [startsCapacity]elementStart
+}
+
+// Starts held at once when decoding most elements
+const startsCapacity = 64
+
+// Where an element starts and how deep it is in the document
+type elementStart struct {
+	name                string
+	depth, line, column int32
+}
+
+func (source *sourceReader) skipElement() error {
+	for depth := 1; depth > 0; {
+		token, err := source.decoder.RawToken()
+		if err != nil {
+			return err
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return nil
+}
+
+func (source *sourceReader) Token() (xml.Token, error) {
+	for {
+		offset := source.decoder.InputOffset()
+		token, err := source.decoder.RawToken()
+		if err != nil {
+			return token, err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			if source.skip[element.Name.Local] {
+				if err := source.skipElement(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			line, column := source.lines.position(offset)
+			source.starts = append(source.starts, elementStart{
+				name:   element.Name.Local,
+				depth:  int32(source.depth),
+				line:   int32(line),
+				column: int32(column),
+			})
+			source.depth++
+		case xml.EndElement:
+			source.depth--
+		}
+		return token, nil
+	}
+}
+
+func (source *sourceReader) position(start elementStart) Position {
+	return Position{File: source.file, Line: int(start.line), Column: int(start.column)}
+}
+
+// The start of the element the decoder was just handed
+func (source *sourceReader) lastStart() int {
+	return len(source.starts) - 1
+}
+
+// Decodes the element the decoder was just handed into v, unless v is nil in which case it's skipped,
+// then sets the positions of the elements decoded and drops their starts
+func (source *sourceReader) decodeElement(decoder *xml.Decoder, v interface{}, start *xml.StartElement) error {
+	first := source.lastStart()
+	var err error
+	if v == nil {
+		err = decoder.Skip()
+	} else {
+		err = decoder.DecodeElement(v, start)
+		source.setPositions(reflect.ValueOf(v), source.starts[first:])
+	}
+	source.starts = source.starts[:first]
+	return err
+}
+
+// Fields of an element type which hold its position and the elements within it that have positions of their own
+type positionFields struct {
+	position int
+	elements map[string]positionElement
+}
+
+type positionElement struct {
+	index int
+	// Which of the counts of the elements seen so far is the one of the field
+	ordinal int
+}
+
+// Most fields of elements with positions an element type can have
+const maxPositionElements = 16
+
+var (
+	positionType        = reflect.TypeOf(Position{})
+	positionFieldsMutex sync.Mutex
+	positionFieldsCache = make(map[reflect.Type]*positionFields)
+)
+
+func fieldsWithPositions(t reflect.Type) *positionFields {
+	positionFieldsMutex.Lock()
+	defer positionFieldsMutex.Unlock()
+	if fields, ok := positionFieldsCache[t]; ok {
+		return fields
+	}
+
+	fields := &positionFields{position: -1, elements: make(map[string]positionElement)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Type == positionType {
+			fields.position = i
+			continue
+		}
+		// Elements nested in a parent element of their own, like the property refs of a key, have no positions
+		tag := strings.Split(field.Tag.Get("xml"), ",")
+		if tag[0] == "" || tag[0] == "-" || strings.Contains(tag[0], ">") || (len(tag) > 1 && tag[1] != "omitempty") {
+			continue
+		}
+		name := tag[0]
+		if len(fields.elements) < maxPositionElements && hasPositions(field.Type, make(map[reflect.Type]bool)) {
+			fields.elements[name] = positionElement{index: i, ordinal: len(fields.elements)}
+		}
+	}
+	positionFieldsCache[t] = fields
+	return fields
+}
+
+// Whether values of the type, or the elements they're made of, have positions
+func hasPositions(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return false
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.Type == positionType || hasPositions(field.Type, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sets the positions of the element decoded into value and of the elements within it from the starts recorded while
+// it was decoded, the first of which is the one of the element itself. The k-th child element of a name is the k-th
+// item of the field it was decoded into, as the decoder appends the elements in the order they come.
+func (source *sourceReader) setPositions(value reflect.Value, starts []elementStart) {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct || len(starts) == 0 {
+		return
+	}
+
+	fields := fieldsWithPositions(value.Type())
+	if fields.position >= 0 {
+		*value.Field(fields.position).Addr().Interface().(*Position) = source.position(starts[0])
+	}
+	if len(fields.elements) == 0 {
+		return
+	}
+
+	var counts [maxPositionElements]int
+	depth := starts[0].depth
+	for i := 1; i < len(starts) && starts[i].depth > depth; i++ {
+		element, ok := fields.elements[starts[i].name]
+		if !ok || starts[i].depth != depth+1 {
+			continue
+		}
+		k := counts[element.ordinal]
+		counts[element.ordinal]++
+
+		field := value.Field(element.index)
+		if field.Kind() == reflect.Slice {
+			if k < field.Len() {
+				source.setPositions(field.Index(k), starts[i:])
+			}
+		} else if k == 0 {
+			source.setPositions(field, starts[i:])
+		}
+	}
+}
+
+// Decodes the elements of the schema one at a time, so that only the element at hand is held besides the model
+func decodeSchema(decoder *xml.Decoder, source *sourceReader, start xml.StartElement) (Schema, error) {
+	schema := Schema{XMLName: start.Name, Position: source.position(source.starts[source.lastStart()])}
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "Namespace":
+			schema.Namespace = attr.Value
+		case "Alias":
+			alias := attr.Value
+			schema.Alias = &alias
+		default:
+			schema.UnknownAttributes.UnmarshalXMLAttr(attr)
+		}
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return schema, err
+		}
+
+		switch element := token.(type) {
+		case xml.EndElement:
+			return schema, nil
+		case xml.StartElement:
+			var v interface{}
+			switch element.Name.Local {
+			case "EntityContainer":
+				schema.EntityContainers = append(schema.EntityContainers, EntityContainer{})
+				v = &schema.EntityContainers[len(schema.EntityContainers)-1]
+			case "EntityType":
+				schema.EntityTypes = append(schema.EntityTypes, EntityType{})
+				v = &schema.EntityTypes[len(schema.EntityTypes)-1]
+			case "ComplexType":
+				schema.ComplexTypes = append(schema.ComplexTypes, ComplexType{})
+				v = &schema.ComplexTypes[len(schema.ComplexTypes)-1]
+			case "EnumType":
+				schema.EnumTypes = append(schema.EnumTypes, EnumType{})
+				v = &schema.EnumTypes[len(schema.EnumTypes)-1]
+			case "TypeDefinition":
+				schema.TypeDefinitions = append(schema.TypeDefinitions, TypeDefinition{})
+				v = &schema.TypeDefinitions[len(schema.TypeDefinitions)-1]
+			case "Function":
+				schema.Functions = append(schema.Functions, Function{})
+				v = &schema.Functions[len(schema.Functions)-1]
+			case "Action":
+				schema.Actions = append(schema.Actions, Action{})
+				v = &schema.Actions[len(schema.Actions)-1]
+			case "Association":
+				schema.Associations = append(schema.Associations, Association{})
+				v = &schema.Associations[len(schema.Associations)-1]
+			case "Annotation":
+				schema.Annotations = append(schema.Annotations, Annotation{})
+				v = &schema.Annotations[len(schema.Annotations)-1]
+			case "Documentation":
+				if schema.Documentation == nil {
+					schema.Documentation = &Documentation{}
+				}
+				v = schema.Documentation
+			case "Annotations":
+				schema.ExternalAnnotations = append(schema.ExternalAnnotations, Annotations{})
+				v = &schema.ExternalAnnotations[len(schema.ExternalAnnotations)-1]
+			}
+			if err := source.decodeElement(decoder, v, &element); err != nil {
+				return schema, err
+			}
+		}
+	}
+}
+
+// Reads CSDL XML from the reader as it goes rather than loading the whole document first.
+// The result is the same as the one of ParseXML, less the skipped elements.
+func ParseReader(r io.Reader, options ParseOptions) (*EdmxDocument, error) {
+	reader, ok := r.(io.ByteReader)
+	if !ok {
+		reader = bufio.NewReader(r)
+	}
+	source := &sourceReader{lines: lineCounter{reader: reader}, file: options.FileName}
+	source.decoder = xml.NewDecoder(&source.lines)
+	source.starts = source.startsBuffer[:0]
+	if len(options.SkipElements) > 0 {
+		source.skip = make(map[string]bool)
+		for _, name := range options.SkipElements {
+			source.skip[name] = true
+		}
+	}
+	decoder := xml.NewTokenDecoder(source)
+
+	var edm EdmxDocument
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 1:
+				if element.Name.Local != "Edmx" {
+					return nil, fmt.Errorf("expected element type <Edmx> but have <%s>", element.Name.Local)
+				}
+				edm.XMLName = element.Name
+				edm.Position = source.position(source.starts[source.lastStart()])
+				for _, attr := range element.Attr {
+					if attr.Name.Local == "Version" {
+						edm.Version = attr.Value
+					} else {
+						edm.UnknownAttributes.UnmarshalXMLAttr(attr)
+					}
+				}
+			case depth == 2 && element.Name.Local == "Reference":
+				edm.References = append(edm.References, Reference{})
+				err = source.decodeElement(decoder, &edm.References[len(edm.References)-1], &element)
+				depth--
+			case depth == 2 && element.Name.Local == "DataServices":
+				edm.DataServices.XMLName = element.Name
+				edm.DataServices.Position = source.position(source.starts[source.lastStart()])
+				for _, attr := range element.Attr {
+					if attr.Name.Local == "DataServiceVersion" {
+						edm.DataServices.DataServiceVersion = attr.Value
+					} else {
+						edm.DataServices.UnknownAttributes.UnmarshalXMLAttr(attr)
+					}
+				}
+			case depth == 3 && element.Name.Local == "Schema":
+				first := source.lastStart()
+				var schema Schema
+				schema, err = decodeSchema(decoder, source, element)
+				edm.DataServices.Schemas = append(edm.DataServices.Schemas, schema)
+				source.starts = source.starts[:first]
+				depth--
+			default:
+				err = source.decodeElement(decoder, nil, &element)
+				depth--
+			}
+			if err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				normalize(&edm)
+				return &edm, nil
+			}
+		}
+	}
+}
@@ -1,159 +1,384 @@
 package odataschema
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// Where an element starts in the document it was read from. Elements read from CSDL JSON or built in code have none.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (position Position) IsValid() bool {
+	return position.Line > 0
+}
+
+func (position Position) String() string {
+	if position.File == "" {
+		return fmt.Sprintf("%d:%d", position.Line, position.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", position.File, position.Line, position.Column)
+}
+
+// Attributes the model doesn't cover, kept for writing the document back.
+// Namespace declarations are left out, as the writer declares the namespaces itself.
+type UnknownAttributes []xml.Attr
+
+func (attributes *UnknownAttributes) UnmarshalXMLAttr(attr xml.Attr) error {
+	if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+		return nil
+	}
+	*attributes = append(*attributes, attr)
+	return nil
+}
+
+// Documentation of CSDL v2/v3 elements, which later versions express with Core annotations
+type Documentation struct {
+	XMLName         xml.Name `xml:"Documentation"`
+	Summary         *string  `xml:"Summary"`
+	LongDescription *string  `xml:"LongDescription"`
+}
 
 type PropertyRef struct {
 	XMLName xml.Name `xml:"PropertyRef"`
-	Name    string   `xml:"Name,attr"`
+	Name    string   `xml:"Name,attr,omitempty"`
 }
 
 type TypeRef struct {
 	XMLName xml.Name `xml:"TypeRef"`
-	Type    string   `xml:"Type,attr"`
+	Type    string   `xml:"Type,attr,omitempty"`
 }
 
 type Property struct {
-	XMLName  xml.Name `xml:"Property"`
-	Name     string   `xml:"Name,attr"`
-	Type     string   `xml:"Type,attr"`
-	TypeRef  *TypeRef `xml:"TypeRef"`
-	Nullable *bool    `xml:"Nullable,attr"`
-	// DefaultValue string   `xml:"DefaultValue,attr"`
-	// MaxLength    string   `xml:"MaxLength,attr"`
-	// FixedLength  string   `xml:"FixedLength,attr"`
-	// Precision    string   `xml:"Precision,attr"`
-	// Scale        string   `xml:"Scale,attr"`
-	// Unicode      string   `xml:"Unicode,attr"`
-	// Collation    string   `xml:"Collation,attr"`
-	// SRID         string   `xml:"SRID,attr"`
+	XMLName       xml.Name       `xml:"Property"`
+	Name          string         `xml:"Name,attr,omitempty"`
+	Type          string         `xml:"Type,attr,omitempty"`
+	TypeRef       *TypeRef       `xml:"TypeRef"`
+	Nullable      *bool          `xml:"Nullable,attr,omitempty"`
+	Annotations   []Annotation   `xml:"Annotation"`
+	Documentation *Documentation `xml:"Documentation"`
+	// DefaultValue string   `xml:"DefaultValue,attr,omitempty"`
+	// MaxLength    string   `xml:"MaxLength,attr,omitempty"`
+	// FixedLength  string   `xml:"FixedLength,attr,omitempty"`
+	// Precision    string   `xml:"Precision,attr,omitempty"`
+	// Scale        string   `xml:"Scale,attr,omitempty"`
+	// Unicode      string   `xml:"Unicode,attr,omitempty"`
+	// Collation    string   `xml:"Collation,attr,omitempty"`
+	// SRID         string   `xml:"SRID,attr,omitempty"`
+	UnknownAttributes UnknownAttributes `xml:",any,attr"`
+	Position          Position          `xml:"-" json:"-"`
+}
+
+type ReferentialConstraint struct {
+	XMLName            xml.Name `xml:"ReferentialConstraint"`
+	Property           string   `xml:"Property,attr,omitempty"`
+	ReferencedProperty string   `xml:"ReferencedProperty,attr,omitempty"`
 }
 
 type NavigationProperty struct {
-	XMLName  xml.Name `xml:"NavigationProperty"`
-	Name     string   `xml:"Name,attr"`
-	Type     string   `xml:"Type,attr"`
-	Nullable *bool    `xml:"Nullable,attr"`
+	XMLName                xml.Name                `xml:"NavigationProperty"`
+	Name                   string                  `xml:"Name,attr,omitempty"`
+	Type                   string                  `xml:"Type,attr,omitempty"`
+	Nullable               *bool                   `xml:"Nullable,attr,omitempty"`
+	Partner                *string                 `xml:"Partner,attr,omitempty"`
+	ContainsTarget         bool                    `xml:"ContainsTarget,attr,omitempty"`
+	Relationship           *string                 `xml:"Relationship,attr,omitempty"`
+	FromRole               string                  `xml:"FromRole,attr,omitempty"`
+	ToRole                 string                  `xml:"ToRole,attr,omitempty"`
+	ReferentialConstraints []ReferentialConstraint `xml:"ReferentialConstraint"`
+	Annotations            []Annotation            `xml:"Annotation"`
+	Documentation          *Documentation          `xml:"Documentation"`
+	UnknownAttributes      UnknownAttributes       `xml:",any,attr"`
+	Position               Position                `xml:"-" json:"-"`
 }
 
 type NavigationPropertyBinding struct {
 	XMLName xml.Name `xml:"NavigationPropertyBinding"`
-	Path    string   `xml:"Path,attr"`
-	Target  string   `xml:"Target,attr"`
+	Path    string   `xml:"Path,attr,omitempty"`
+	Target  string   `xml:"Target,attr,omitempty"`
 }
 
 type EnumTypeMember struct {
-	XMLName xml.Name `xml:"Member"`
-	Name    string   `xml:"Name,attr"`
-	Value   string   `xml:"Value,attr"`
+	XMLName           xml.Name          `xml:"Member"`
+	Name              string            `xml:"Name,attr,omitempty"`
+	Value             string            `xml:"Value,attr,omitempty"`
+	Annotations       []Annotation      `xml:"Annotation"`
+	Documentation     *Documentation    `xml:"Documentation"`
+	UnknownAttributes UnknownAttributes `xml:",any,attr"`
+	Position          Position          `xml:"-" json:"-"`
 }
 
 type EnumType struct {
-	XMLName        xml.Name         `xml:"EnumType"`
-	Name           string           `xml:"Name,attr"`
-	UnderlyingType string           `xml:"UnderlyingType,attr"`
-	IsFlags        bool             `xml:"IsFlags,attr"`
-	Members        []EnumTypeMember `xml:"Member"`
+	XMLName           xml.Name          `xml:"EnumType"`
+	Name              string            `xml:"Name,attr,omitempty"`
+	UnderlyingType    string            `xml:"UnderlyingType,attr,omitempty"`
+	IsFlags           bool              `xml:"IsFlags,attr,omitempty"`
+	Members           []EnumTypeMember  `xml:"Member"`
+	Annotations       []Annotation      `xml:"Annotation"`
+	Documentation     *Documentation    `xml:"Documentation"`
+	UnknownAttributes UnknownAttributes `xml:",any,attr"`
+	Position          Position          `xml:"-" json:"-"`
+}
+
+// Named primitive type with facets, e.g. a "Money" type based on Edm.Decimal with a precision and a scale
+type TypeDefinition struct {
+	XMLName           xml.Name          `xml:"TypeDefinition"`
+	Name              string            `xml:"Name,attr,omitempty"`
+	UnderlyingType    string            `xml:"UnderlyingType,attr,omitempty"`
+	MaxLength         *string           `xml:"MaxLength,attr,omitempty"`
+	Precision         *string           `xml:"Precision,attr,omitempty"`
+	Scale             *string           `xml:"Scale,attr,omitempty"`
+	SRID              *string           `xml:"SRID,attr,omitempty"`
+	Unicode           *bool             `xml:"Unicode,attr,omitempty"`
+	Annotations       []Annotation      `xml:"Annotation"`
+	Documentation     *Documentation    `xml:"Documentation"`
+	UnknownAttributes UnknownAttributes `xml:",any,attr"`
+	Position          Position          `xml:"-" json:"-"`
 }
 
 type ComplexType struct {
 	XMLName              xml.Name             `xml:"ComplexType"`
-	Name                 string               `xml:"Name,attr"`
-	BaseType             *string              `xml:"BaseType"`
-	Abstract             bool                 `xml:"Abstract,attr"`
+	Name                 string               `xml:"Name,attr,omitempty"`
+	BaseType             *string              `xml:"BaseType,attr,omitempty"`
+	Abstract             bool                 `xml:"Abstract,attr,omitempty"`
 	Properties           []Property           `xml:"Property"`
-	OpenType             bool                 `xml:"OpenType,attr"`
+	OpenType             bool                 `xml:"OpenType,attr,omitempty"`
 	NavigationProperties []NavigationProperty `xml:"NavigationProperty"`
+	Annotations          []Annotation         `xml:"Annotation"`
+	Documentation        *Documentation       `xml:"Documentation"`
+	UnknownAttributes    UnknownAttributes    `xml:",any,attr"`
+	Position             Position             `xml:"-" json:"-"`
 }
 
 type EntityType struct {
 	XMLName              xml.Name             `xml:"EntityType"`
-	Name                 string               `xml:"Name,attr"`
-	HasStream            bool                 `xml:"HasStream,attr"`
-	BaseType             *string              `xml:"BaseType,attr"`
-	Abstract             bool                 `xml:"Abstract,attr"`
-	OpenType             bool                 `xml:"OpenType,attr"`
+	Name                 string               `xml:"Name,attr,omitempty"`
+	HasStream            bool                 `xml:"HasStream,attr,omitempty"`
+	BaseType             *string              `xml:"BaseType,attr,omitempty"`
+	Abstract             bool                 `xml:"Abstract,attr,omitempty"`
+	OpenType             bool                 `xml:"OpenType,attr,omitempty"`
 	Key                  *[]PropertyRef       `xml:">PropertyRef"`
 	Properties           []Property           `xml:"Property"`
 	NavigationProperties []NavigationProperty `xml:"NavigationProperty"`
+	Annotations          []Annotation         `xml:"Annotation"`
+	Documentation        *Documentation       `xml:"Documentation"`
+	UnknownAttributes    UnknownAttributes    `xml:",any,attr"`
+	Position             Position             `xml:"-" json:"-"`
 }
 
 type EntitySet struct {
 	XMLName                    xml.Name                    `xml:"EntitySet"`
-	Name                       string                      `xml:"Name,attr"`
-	EntityType                 string                      `xml:"EntityType,attr"`
+	Name                       string                      `xml:"Name,attr,omitempty"`
+	EntityType                 string                      `xml:"EntityType,attr,omitempty"`
+	NavigationPropertyBindings []NavigationPropertyBinding `xml:"NavigationPropertyBinding"`
+	Annotations                []Annotation                `xml:"Annotation"`
+	Documentation              *Documentation              `xml:"Documentation"`
+	UnknownAttributes          UnknownAttributes           `xml:",any,attr"`
+	Position                   Position                    `xml:"-" json:"-"`
+}
+
+type Singleton struct {
+	XMLName                    xml.Name                    `xml:"Singleton"`
+	Name                       string                      `xml:"Name,attr,omitempty"`
+	Type                       string                      `xml:"Type,attr,omitempty"`
 	NavigationPropertyBindings []NavigationPropertyBinding `xml:"NavigationPropertyBinding"`
+	Annotations                []Annotation                `xml:"Annotation"`
+	Documentation              *Documentation              `xml:"Documentation"`
+	UnknownAttributes          UnknownAttributes           `xml:",any,attr"`
+	Position                   Position                    `xml:"-" json:"-"`
 }
 
 type EntityContainer struct {
-	Name            string           `xml:"Name,attr"`
+	Name string `xml:"Name,attr,omitempty"`
+	// Qualified name of the container whose elements the container includes
+	Extends         *string          `xml:"Extends,attr,omitempty"`
 	EntitySets      []EntitySet      `xml:"EntitySet"`
+	AssociationSets []AssociationSet `xml:"AssociationSet"`
+	Singletons      []Singleton      `xml:"Singleton"`
 	FunctionImports []FunctionImport `xml:"FunctionImport"`
 	ActionImports   []ActionImport   `xml:"ActionImport"`
+	Annotations     []Annotation     `xml:"Annotation"`
+	Documentation   *Documentation   `xml:"Documentation"`
+	// Whether it's the container of the service, which is the only container of a document or else the one
+	// CSDL v2/v3 documents mark with m:IsDefaultEntityContainer and CSDL JSON documents name at their root
+	IsDefault         bool              `xml:"-"`
+	UnknownAttributes UnknownAttributes `xml:",any,attr"`
+	Position          Position          `xml:"-" json:"-"`
 }
 
 type Schema struct {
-	XMLName         xml.Name         `xml:"Schema"`
-	Namespace       string           `xml:"Namespace,attr"`
-	Alias           *string          `xml:"Alias,attr"`
-	EntityContainer *EntityContainer `xml:"EntityContainer"`
-	EntityTypes     []EntityType     `xml:"EntityType"`
-	ComplexTypes    []ComplexType    `xml:"ComplexType"`
-	EnumTypes       []EnumType       `xml:"EnumType"`
-	Functions       []Function       `xml:"Function"`
-	Actions         []Action         `xml:"Action"`
+	XMLName             xml.Name          `xml:"Schema"`
+	Namespace           string            `xml:"Namespace,attr,omitempty"`
+	Alias               *string           `xml:"Alias,attr,omitempty"`
+	EntityContainers    []EntityContainer `xml:"EntityContainer"`
+	EntityTypes         []EntityType      `xml:"EntityType"`
+	ComplexTypes        []ComplexType     `xml:"ComplexType"`
+	EnumTypes           []EnumType        `xml:"EnumType"`
+	TypeDefinitions     []TypeDefinition  `xml:"TypeDefinition"`
+	Functions           []Function        `xml:"Function"`
+	Actions             []Action          `xml:"Action"`
+	Associations        []Association     `xml:"Association"`
+	Annotations         []Annotation      `xml:"Annotation"`
+	Documentation       *Documentation    `xml:"Documentation"`
+	ExternalAnnotations []Annotations     `xml:"Annotations"`
+	UnknownAttributes   UnknownAttributes `xml:",any,attr"`
+	Position            Position          `xml:"-" json:"-"`
 }
 
 type DataServices struct {
-	XMLName xml.Name `xml:"DataServices"`
-	Schemas []Schema `xml:"Schema"`
+	XMLName            xml.Name          `xml:"DataServices"`
+	DataServiceVersion string            `xml:"DataServiceVersion,attr,omitempty"`
+	Schemas            []Schema          `xml:"Schema"`
+	UnknownAttributes  UnknownAttributes `xml:",any,attr"`
+	Position           Position          `xml:"-" json:"-"`
+}
+
+type Include struct {
+	XMLName   xml.Name `xml:"Include"`
+	Namespace string   `xml:"Namespace,attr,omitempty"`
+	Alias     *string  `xml:"Alias,attr,omitempty"`
+}
+
+type IncludeAnnotations struct {
+	XMLName         xml.Name `xml:"IncludeAnnotations"`
+	TermNamespace   string   `xml:"TermNamespace,attr,omitempty"`
+	Qualifier       *string  `xml:"Qualifier,attr,omitempty"`
+	TargetNamespace *string  `xml:"TargetNamespace,attr,omitempty"`
+}
+
+type Reference struct {
+	XMLName            xml.Name             `xml:"Reference"`
+	Uri                string               `xml:"Uri,attr,omitempty"`
+	Includes           []Include            `xml:"Include"`
+	IncludeAnnotations []IncludeAnnotations `xml:"IncludeAnnotations"`
 }
 
 type EdmxDocument struct {
-	XMLName      xml.Name     `xml:"Edmx"`
-	DataServices DataServices `xml:"DataServices"`
+	XMLName           xml.Name          `xml:"Edmx"`
+	Version           string            `xml:"Version,attr,omitempty"`
+	References        []Reference       `xml:"Reference"`
+	DataServices      DataServices      `xml:"DataServices"`
+	UnknownAttributes UnknownAttributes `xml:",any,attr"`
+	Position          Position          `xml:"-" json:"-"`
 }
 
 type ReturnType struct {
-	XMLName  xml.Name `xml:"ReturnType"`
-	Type     string   `xml:",attr"`
-	Nullable bool     `xml:",attr"`
+	XMLName           xml.Name          `xml:"ReturnType"`
+	Type              string            `xml:",attr,omitempty"`
+	Nullable          *bool             `xml:",attr,omitempty"`
+	UnknownAttributes UnknownAttributes `xml:",any,attr"`
+	Position          Position          `xml:"-" json:"-"`
 }
 
 type Parameter struct {
-	XMLName xml.Name `xml:"Parameter"`
-	Name    string   `xml:",attr"`
-	Type    string   `xml:",attr"`
+	XMLName           xml.Name          `xml:"Parameter"`
+	Name              string            `xml:",attr,omitempty"`
+	Type              string            `xml:",attr,omitempty"`
+	Nullable          *bool             `xml:",attr,omitempty"`
+	Annotations       []Annotation      `xml:"Annotation"`
+	Documentation     *Documentation    `xml:"Documentation"`
+	UnknownAttributes UnknownAttributes `xml:",any,attr"`
+	Position          Position          `xml:"-" json:"-"`
 }
 
 type Function struct {
-	XMLName       xml.Name    `xml:"Function"`
-	Name          string      `xml:",attr"`
-	IsBound       bool        `xml:",attr"`
-	EntitySetPath *string     `xml:",attr"`
-	IsComposable  bool        `xml:",attr"`
-	Parameters    []Parameter `xml:"Parameter"`
-	ReturnType    ReturnType  `xml:"ReturnType"`
+	XMLName           xml.Name          `xml:"Function"`
+	Name              string            `xml:",attr,omitempty"`
+	IsBound           bool              `xml:",attr,omitempty"`
+	EntitySetPath     *string           `xml:",attr,omitempty"`
+	IsComposable      bool              `xml:",attr,omitempty"`
+	Parameters        []Parameter       `xml:"Parameter"`
+	ReturnType        ReturnType        `xml:"ReturnType"`
+	Annotations       []Annotation      `xml:"Annotation"`
+	Documentation     *Documentation    `xml:"Documentation"`
+	UnknownAttributes UnknownAttributes `xml:",any,attr"`
+	Position          Position          `xml:"-" json:"-"`
 }
 
 type Action struct {
-	XMLName       xml.Name    `xml:"Action"`
-	Name          string      `xml:",attr"`
-	IsBound       bool        `xml:",attr"`
-	EntitySetPath *string     `xml:",attr"`
-	Parameters    []Parameter `xml:"Parameter"`
-	ReturnType    *ReturnType `xml:"ReturnType"`
+	XMLName           xml.Name          `xml:"Action"`
+	Name              string            `xml:",attr,omitempty"`
+	IsBound           bool              `xml:",attr,omitempty"`
+	EntitySetPath     *string           `xml:",attr,omitempty"`
+	Parameters        []Parameter       `xml:"Parameter"`
+	ReturnType        *ReturnType       `xml:"ReturnType"`
+	Annotations       []Annotation      `xml:"Annotation"`
+	Documentation     *Documentation    `xml:"Documentation"`
+	UnknownAttributes UnknownAttributes `xml:",any,attr"`
+	Position          Position          `xml:"-" json:"-"`
 }
 
 type FunctionImport struct {
 	XMLName   xml.Name `xml:"FunctionImport"`
-	Name      string   `xml:",attr"`
-	EntitySet string   `xml:",attr"`
-	Function  string   `xml:",attr"`
+	Name      string   `xml:",attr,omitempty"`
+	EntitySet string   `xml:",attr,omitempty"`
+	Function  string   `xml:",attr,omitempty"`
+	// CSDL v2/v3 declare the operation on the import itself
+	ReturnType        *string           `xml:"ReturnType,attr,omitempty"`
+	HttpMethod        *string           `xml:"HttpMethod,attr,omitempty"`
+	IsBindable        bool              `xml:"IsBindable,attr,omitempty"`
+	IsSideEffecting   *bool             `xml:"IsSideEffecting,attr,omitempty"`
+	Parameters        []Parameter       `xml:"Parameter"`
+	Annotations       []Annotation      `xml:"Annotation"`
+	Documentation     *Documentation    `xml:"Documentation"`
+	UnknownAttributes UnknownAttributes `xml:",any,attr"`
+	Position          Position          `xml:"-" json:"-"`
 }
 
 type ActionImport struct {
-	XMLName   xml.Name `xml:"ActionImport"`
-	Name      string   `xml:",attr"`
-	EntitySet string   `xml:",attr"`
-	Action    string   `xml:",attr"`
+	XMLName           xml.Name          `xml:"ActionImport"`
+	Name              string            `xml:",attr,omitempty"`
+	EntitySet         string            `xml:",attr,omitempty"`
+	Action            string            `xml:",attr,omitempty"`
+	Annotations       []Annotation      `xml:"Annotation"`
+	Documentation     *Documentation    `xml:"Documentation"`
+	UnknownAttributes UnknownAttributes `xml:",any,attr"`
+	Position          Position          `xml:"-" json:"-"`
+}
+
+type AssociationEnd struct {
+	XMLName      xml.Name `xml:"End"`
+	Role         string   `xml:"Role,attr,omitempty"`
+	Type         string   `xml:"Type,attr,omitempty"`
+	Multiplicity string   `xml:"Multiplicity,attr,omitempty"`
+}
+
+type AssociationRole struct {
+	Role         string        `xml:"Role,attr,omitempty"`
+	PropertyRefs []PropertyRef `xml:"PropertyRef"`
+}
+
+type AssociationConstraint struct {
+	XMLName   xml.Name        `xml:"ReferentialConstraint"`
+	Principal AssociationRole `xml:"Principal"`
+	Dependent AssociationRole `xml:"Dependent"`
+}
+
+// Relationship between entity types in CSDL v2/v3, which CSDL v4 expresses with navigation properties only
+type Association struct {
+	XMLName               xml.Name               `xml:"Association"`
+	Name                  string                 `xml:"Name,attr,omitempty"`
+	Ends                  []AssociationEnd       `xml:"End"`
+	ReferentialConstraint *AssociationConstraint `xml:"ReferentialConstraint"`
+	UnknownAttributes     UnknownAttributes      `xml:",any,attr"`
+	Position              Position               `xml:"-" json:"-"`
+}
+
+type AssociationSetEnd struct {
+	XMLName   xml.Name `xml:"End"`
+	Role      string   `xml:"Role,attr,omitempty"`
+	EntitySet string   `xml:"EntitySet,attr,omitempty"`
+}
+
+// Entity sets related by an association in CSDL v2/v3, which CSDL v4 expresses with navigation property bindings
+type AssociationSet struct {
+	XMLName           xml.Name            `xml:"AssociationSet"`
+	Name              string              `xml:"Name,attr,omitempty"`
+	Association       string              `xml:"Association,attr,omitempty"`
+	Ends              []AssociationSetEnd `xml:"End"`
+	UnknownAttributes UnknownAttributes   `xml:",any,attr"`
+	Position          Position            `xml:"-" json:"-"`
 }
@@ -0,0 +1,698 @@
+package odataschema
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Kinds of diagnostics, so that callers can tell them apart without parsing messages
+const (
+	DiagnosticInvalidName           = "invalid name"
+	DiagnosticDuplicateName         = "duplicate name"
+	DiagnosticUnresolved            = "unresolved reference"
+	DiagnosticMissingKey            = "missing key"
+	DiagnosticInvalidKey            = "invalid key"
+	DiagnosticInvalidOperation      = "invalid operation"
+	DiagnosticMissingBinding        = "missing binding parameter"
+	DiagnosticInvalidEnum           = "invalid enum"
+	DiagnosticInvalidTypeDefinition = "invalid type definition"
+	DiagnosticIgnoredAttribute      = "ignored attribute"
+	DiagnosticUnsupportedType       = "unsupported type"
+)
+
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Position Position
+	// The qualified name or path of the element the diagnostic is about, e.g. "Trippin.Person/Emails"
+	Target  string
+	Message string
+}
+
+func (diagnostic Diagnostic) String() string {
+	message := fmt.Sprintf("%s: %s: %s", diagnostic.Severity, diagnostic.Target, diagnostic.Message)
+	if diagnostic.Position.IsValid() {
+		return fmt.Sprintf("%s: %s", diagnostic.Position, message)
+	}
+	return message
+}
+
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	simpleIdentifier = regexp.MustCompile(`^[\p{L}\p{Nl}_][\p{L}\p{Nl}\p{Nd}\p{Mn}\p{Mc}\p{Pc}\p{Cf}]{0,127}$`)
+	primitiveTypes   = map[string]bool{}
+	// Primitive types which stand for any value of their kind, and so can't underlie type definitions
+	abstractTypes = map[string]bool{"Edm.PrimitiveType": true, "Edm.ComplexType": true, "Edm.EntityType": true, "Edm.Untyped": true}
+	// Primitive types the mediation and GraphQL schemas can represent, the elements of the others can't be mapped
+	supportedTypes = map[string]bool{}
+)
+
+func init() {
+	for _, name := range []string{
+		"Binary", "Boolean", "Byte", "Date", "DateTimeOffset", "Decimal", "Double", "Duration", "Guid",
+		"Int16", "Int32", "Int64", "SByte", "Single", "Stream", "String", "TimeOfDay",
+		"Geography", "GeographyPoint", "GeographyLineString", "GeographyPolygon", "GeographyMultiPoint",
+		"GeographyMultiLineString", "GeographyMultiPolygon", "GeographyCollection",
+		"Geometry", "GeometryPoint", "GeometryLineString", "GeometryPolygon", "GeometryMultiPoint",
+		"GeometryMultiLineString", "GeometryMultiPolygon", "GeometryCollection",
+		"PrimitiveType", "ComplexType", "EntityType", "Untyped",
+		"AnnotationPath", "PropertyPath", "NavigationPropertyPath", "ModelElementPath", "AnyPropertyPath",
+	} {
+		primitiveTypes["Edm."+name] = true
+	}
+	for _, name := range []string{
+		"Binary", "Boolean", "Byte", "Date", "DateTimeOffset", "Decimal", "Double", "Duration", "Guid",
+		"Int16", "Int32", "Int64", "SByte", "Single", "Stream", "String", "GeographyPoint",
+	} {
+		supportedTypes["Edm."+name] = true
+	}
+}
+
+type validator struct {
+	diagnostics     []Diagnostic
+	aliases         map[string]string
+	referenced      map[string]bool
+	entityTypes     map[string]*EntityType
+	complexTypes    map[string]*ComplexType
+	enumTypes       map[string]*EnumType
+	typeDefinitions map[string]*TypeDefinition
+	functions       map[string][]*Function
+	actions         map[string][]*Action
+	containers      map[string]*EntityContainer
+}
+
+func (v *validator) report(severity Severity, code string, position Position, target string, format string, args ...interface{}) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Position: position,
+		Target:   target,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Replaces the alias a qualified name starts with by the namespace it stands for
+func (v *validator) qualify(name string) string {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return name
+	}
+	if namespace, ok := v.aliases[name[:i]]; ok {
+		return namespace + name[i:]
+	}
+	return name
+}
+
+// Names of other documents can't be resolved, so they're taken as they are
+func (v *validator) isReferenced(name string) bool {
+	i := strings.LastIndex(name, ".")
+	return i >= 0 && v.referenced[name[:i]]
+}
+
+func (v *validator) isStructuredType(name string) bool {
+	_, isEntityType := v.entityTypes[name]
+	_, isComplexType := v.complexTypes[name]
+	return isEntityType || isComplexType
+}
+
+func (v *validator) resolvesType(typeName string) bool {
+	name, _ := unwrapCollection(typeName)
+	name = v.qualify(name)
+	if primitiveTypes[name] || v.isReferenced(name) || v.isStructuredType(name) {
+		return true
+	}
+	_, isEnumType := v.enumTypes[name]
+	_, isTypeDefinition := v.typeDefinitions[name]
+	return isEnumType || isTypeDefinition
+}
+
+func unwrapCollection(typeName string) (string, bool) {
+	if strings.HasPrefix(typeName, "Collection(") && strings.HasSuffix(typeName, ")") {
+		return typeName[len("Collection(") : len(typeName)-1], true
+	}
+	return typeName, false
+}
+
+func (v *validator) checkType(typeName string, position Position, target string) {
+	if typeName == "" {
+		v.report(SeverityError, DiagnosticUnresolved, position, target, "no type is specified")
+	} else if !v.resolvesType(typeName) {
+		v.report(SeverityError, DiagnosticUnresolved, position, target, "type '%s' is not defined", typeName)
+	} else {
+		v.checkSupportedType(typeName, position, target)
+	}
+}
+
+func (v *validator) checkSupportedType(typeName string, position Position, target string) {
+	name, _ := unwrapCollection(typeName)
+	if name = v.qualify(name); primitiveTypes[name] && !supportedTypes[name] {
+		v.report(SeverityWarning, DiagnosticUnsupportedType, position, target, "type '%s' can't be mapped", typeName)
+	}
+}
+
+// Attributes differing from the known ones in case only, e.g. "baseType", are ignored when reading the document
+func (v *validator) checkAttributes(attributes UnknownAttributes, position Position, target string, known ...string) {
+	for _, attr := range attributes {
+		if attr.Name.Space != "" {
+			continue
+		}
+		for _, name := range known {
+			if strings.EqualFold(attr.Name.Local, name) {
+				v.report(SeverityWarning, DiagnosticIgnoredAttribute, position, target, "attribute '%s' is ignored, did you mean '%s'?", attr.Name.Local, name)
+			}
+		}
+	}
+}
+
+// The properties of a structured type, including the ones it inherits
+func (v *validator) properties(typeName string) ([]Property, []NavigationProperty) {
+	var properties []Property
+	var navProps []NavigationProperty
+	for visited := map[string]bool{}; typeName != "" && !visited[typeName]; {
+		visited[typeName] = true
+		var baseType *string
+		if entityType, ok := v.entityTypes[typeName]; ok {
+			properties = append(properties, entityType.Properties...)
+			navProps = append(navProps, entityType.NavigationProperties...)
+			baseType = entityType.BaseType
+		} else if complexType, ok := v.complexTypes[typeName]; ok {
+			properties = append(properties, complexType.Properties...)
+			navProps = append(navProps, complexType.NavigationProperties...)
+			baseType = complexType.BaseType
+		}
+		typeName = ""
+		if baseType != nil {
+			typeName = v.qualify(*baseType)
+		}
+	}
+	return properties, navProps
+}
+
+func (v *validator) findProperty(typeName string, name string) *Property {
+	properties, _ := v.properties(typeName)
+	for i := range properties {
+		if properties[i].Name == name {
+			return &properties[i]
+		}
+	}
+	return nil
+}
+
+func (v *validator) findNavigationProperty(typeName string, name string) *NavigationProperty {
+	_, navProps := v.properties(typeName)
+	for i := range navProps {
+		if navProps[i].Name == name {
+			return &navProps[i]
+		}
+	}
+	return nil
+}
+
+func (v *validator) checkBaseType(baseType *string, isEntityType bool, qualifiedName string, position Position) {
+	if baseType == nil {
+		return
+	}
+
+	name := v.qualify(*baseType)
+	_, entityTypeFound := v.entityTypes[name]
+	_, complexTypeFound := v.complexTypes[name]
+	if v.isReferenced(name) {
+		return
+	}
+	if (isEntityType && !entityTypeFound) || (!isEntityType && !complexTypeFound) {
+		v.report(SeverityError, DiagnosticUnresolved, position, qualifiedName, "base type '%s' is not defined", *baseType)
+		return
+	}
+
+	// The chain of base types mustn't lead back to the type
+	for visited := map[string]bool{qualifiedName: true}; name != ""; {
+		if visited[name] {
+			v.report(SeverityError, DiagnosticUnresolved, position, qualifiedName, "the type inherits from itself")
+			return
+		}
+		visited[name] = true
+		var next *string
+		if entityType, ok := v.entityTypes[name]; ok {
+			next = entityType.BaseType
+		} else if complexType, ok := v.complexTypes[name]; ok {
+			next = complexType.BaseType
+		}
+		name = ""
+		if next != nil {
+			name = v.qualify(*next)
+		}
+	}
+}
+
+func (v *validator) checkProperties(qualifiedName string, properties []Property, navProps []NavigationProperty) {
+	names := make(map[string]bool)
+	checkName := func(name string, position Position) {
+		target := fmt.Sprintf("%s/%s", qualifiedName, name)
+		if !simpleIdentifier.MatchString(name) {
+			v.report(SeverityError, DiagnosticInvalidName, position, target, "'%s' is not a valid property name", name)
+		}
+		if names[name] {
+			v.report(SeverityError, DiagnosticDuplicateName, position, target, "the property is defined more than once")
+		}
+		names[name] = true
+	}
+
+	for _, property := range properties {
+		checkName(property.Name, property.Position)
+		target := fmt.Sprintf("%s/%s", qualifiedName, property.Name)
+		v.checkType(property.Type, property.Position, target)
+		v.checkAttributes(property.UnknownAttributes, property.Position, target, "Name", "Type", "Nullable")
+	}
+
+	for _, navProp := range navProps {
+		checkName(navProp.Name, navProp.Position)
+		target := fmt.Sprintf("%s/%s", qualifiedName, navProp.Name)
+		v.checkAttributes(navProp.UnknownAttributes, navProp.Position, target, "Name", "Type", "Nullable", "Partner", "ContainsTarget")
+
+		typeName, _ := unwrapCollection(navProp.Type)
+		typeName = v.qualify(typeName)
+		if typeName == "Edm.EntityType" {
+			v.checkSupportedType(navProp.Type, navProp.Position, target)
+			continue
+		}
+		if v.isReferenced(typeName) {
+			continue
+		}
+		if _, ok := v.entityTypes[typeName]; !ok {
+			v.report(SeverityError, DiagnosticUnresolved, navProp.Position, target, "entity type '%s' is not defined", navProp.Type)
+			continue
+		}
+
+		if navProp.Partner != nil && v.findNavigationProperty(typeName, *navProp.Partner) == nil {
+			v.report(SeverityError, DiagnosticUnresolved, navProp.Position, target, "partner '%s' is not a navigation property of '%s'", *navProp.Partner, typeName)
+		}
+		for _, constraint := range navProp.ReferentialConstraints {
+			if v.findProperty(qualifiedName, constraint.Property) == nil {
+				v.report(SeverityError, DiagnosticUnresolved, navProp.Position, target, "constrained property '%s' is not defined", constraint.Property)
+			}
+			if v.findProperty(typeName, constraint.ReferencedProperty) == nil {
+				v.report(SeverityError, DiagnosticUnresolved, navProp.Position, target, "referenced property '%s' is not a property of '%s'", constraint.ReferencedProperty, typeName)
+			}
+		}
+	}
+}
+
+func (v *validator) checkEntityType(qualifiedName string, entityType *EntityType) {
+	v.checkAttributes(entityType.UnknownAttributes, entityType.Position, qualifiedName, "Name", "BaseType", "Abstract", "OpenType", "HasStream")
+	v.checkBaseType(entityType.BaseType, true, qualifiedName, entityType.Position)
+	v.checkProperties(qualifiedName, entityType.Properties, entityType.NavigationProperties)
+
+	if entityType.Key == nil {
+		if entityType.BaseType == nil && !entityType.Abstract {
+			v.report(SeverityError, DiagnosticMissingKey, entityType.Position, qualifiedName, "the entity type has neither a key nor a base type")
+		}
+		return
+	}
+	if entityType.BaseType != nil {
+		v.report(SeverityError, DiagnosticInvalidKey, entityType.Position, qualifiedName, "the entity type declares a key while inheriting the one of '%s'", *entityType.BaseType)
+	}
+
+	for _, keyRef := range *entityType.Key {
+		// Key properties of complex properties are referenced by paths
+		property := v.findProperty(qualifiedName, strings.Split(keyRef.Name, "/")[0])
+		if property == nil {
+			v.report(SeverityError, DiagnosticInvalidKey, entityType.Position, qualifiedName, "key property '%s' is not defined", keyRef.Name)
+		} else if property.Nullable != nil && *property.Nullable && !strings.Contains(keyRef.Name, "/") {
+			v.report(SeverityError, DiagnosticInvalidKey, property.Position, qualifiedName, "key property '%s' is nullable", keyRef.Name)
+		}
+	}
+}
+
+func (v *validator) checkEnumType(qualifiedName string, enumType *EnumType) {
+	underlyingType := enumType.Underlying()
+	if _, ok := enumRanges[underlyingType]; !ok {
+		v.report(SeverityError, DiagnosticInvalidEnum, enumType.Position, qualifiedName, "underlying type '%s' is not an integer type", underlyingType)
+		underlyingType = "Edm.Int64"
+	}
+
+	names := make(map[string]bool)
+	valued := 0
+	for _, member := range enumType.Members {
+		target := fmt.Sprintf("%s/%s", qualifiedName, member.Name)
+		if names[member.Name] {
+			v.report(SeverityError, DiagnosticDuplicateName, member.Position, target, "the member is defined more than once")
+		}
+		names[member.Name] = true
+
+		if member.Value == "" {
+			continue
+		}
+		valued++
+		if value, err := ParseEnumValue(member.Value, underlyingType); err != nil {
+			v.report(SeverityError, DiagnosticInvalidEnum, member.Position, target, "%s", err)
+		} else if enumType.IsFlags && value < 0 {
+			v.report(SeverityError, DiagnosticInvalidEnum, member.Position, target, "the values of a flags enum type can't be negative")
+		}
+	}
+
+	if enumType.IsFlags && valued < len(enumType.Members) {
+		v.report(SeverityError, DiagnosticInvalidEnum, enumType.Position, qualifiedName, "the members of a flags enum type must all have values")
+	} else if valued > 0 && valued < len(enumType.Members) {
+		v.report(SeverityError, DiagnosticInvalidEnum, enumType.Position, qualifiedName, "either all or none of the members must have values")
+	}
+}
+
+func (v *validator) checkTypeDefinition(qualifiedName string, typeDefinition *TypeDefinition) {
+	underlyingType := typeDefinition.UnderlyingType
+	switch {
+	case underlyingType == "":
+		v.report(SeverityError, DiagnosticInvalidTypeDefinition, typeDefinition.Position, qualifiedName, "no underlying type is specified")
+	case !primitiveTypes[underlyingType] || abstractTypes[underlyingType] || strings.HasSuffix(underlyingType, "Path"):
+		v.report(SeverityError, DiagnosticInvalidTypeDefinition, typeDefinition.Position, qualifiedName, "underlying type '%s' is not a primitive type", underlyingType)
+	default:
+		v.checkSupportedType(underlyingType, typeDefinition.Position, qualifiedName)
+	}
+}
+
+func (v *validator) checkOperation(qualifiedName string, isBound bool, entitySetPath *string, parameters []Parameter, returnType *ReturnType, position Position) {
+	if isBound && len(parameters) == 0 {
+		v.report(SeverityError, DiagnosticMissingBinding, position, qualifiedName, "the operation is bound but has no binding parameter")
+	}
+	if entitySetPath != nil && !isBound {
+		v.report(SeverityError, DiagnosticInvalidOperation, position, qualifiedName, "the operation has an entity set path but isn't bound")
+	}
+
+	names := make(map[string]bool)
+	for _, parameter := range parameters {
+		target := fmt.Sprintf("%s/%s", qualifiedName, parameter.Name)
+		if names[parameter.Name] {
+			v.report(SeverityError, DiagnosticDuplicateName, parameter.Position, target, "the parameter is defined more than once")
+		}
+		names[parameter.Name] = true
+		v.checkType(parameter.Type, parameter.Position, target)
+	}
+
+	if returnType != nil && returnType.Type != "" {
+		v.checkType(returnType.Type, returnType.Position, qualifiedName)
+	}
+}
+
+// Follows a navigation property binding path, e.g. "Trips/Trippin.Flight/Airline", from the entity type of the source
+func (v *validator) resolvesBindingPath(typeName string, path string) bool {
+	for _, segment := range strings.Split(path, "/") {
+		segment = v.qualify(segment)
+		if v.isReferenced(typeName) || v.isReferenced(segment) {
+			return true
+		}
+		if v.isStructuredType(segment) {
+			typeName = segment
+		} else if navProp := v.findNavigationProperty(typeName, segment); navProp != nil {
+			// Collections are unwrapped first, as "Collection(Alias.Type)" doesn't start with the alias
+			typeName, _ = unwrapCollection(navProp.Type)
+			typeName = v.qualify(typeName)
+		} else if property := v.findProperty(typeName, segment); property != nil {
+			typeName, _ = unwrapCollection(property.Type)
+			typeName = v.qualify(typeName)
+		} else {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *validator) resolvesBindingTarget(containerName string, container *EntityContainer, target string) bool {
+	if i := strings.LastIndex(target, "/"); i >= 0 {
+		containerName = v.qualify(target[:i])
+		target = target[i+1:]
+		if v.isReferenced(containerName) {
+			return true
+		}
+		container = v.containers[containerName]
+	}
+	// The elements of the containers the container extends are its own as well
+	visited := make(map[*EntityContainer]bool)
+	for container != nil && !visited[container] {
+		visited[container] = true
+		for _, entitySet := range container.EntitySets {
+			if entitySet.Name == target {
+				return true
+			}
+		}
+		for _, singleton := range container.Singletons {
+			if singleton.Name == target {
+				return true
+			}
+		}
+		if container.Extends == nil {
+			return false
+		}
+		extends := v.qualify(*container.Extends)
+		if v.isReferenced(extends) {
+			return true
+		}
+		container = v.containers[extends]
+	}
+	return false
+}
+
+func (v *validator) checkExtends(containerName string, container *EntityContainer) {
+	visited := map[string]bool{containerName: true}
+	for extends := container.Extends; extends != nil; {
+		name := v.qualify(*extends)
+		if v.isReferenced(name) {
+			return
+		}
+		extended, ok := v.containers[name]
+		if !ok {
+			v.report(SeverityError, DiagnosticUnresolved, container.Position, containerName, "entity container '%s' is not defined", *extends)
+			return
+		}
+		if visited[name] {
+			v.report(SeverityError, DiagnosticUnresolved, container.Position, containerName, "the containers extended through '%s' form a cycle", *extends)
+			return
+		}
+		visited[name] = true
+		extends = extended.Extends
+	}
+}
+
+func (v *validator) checkBindings(containerName string, container *EntityContainer, sourceName string, typeName string, bindings []NavigationPropertyBinding, position Position) {
+	for _, binding := range bindings {
+		target := fmt.Sprintf("%s/%s", containerName, sourceName)
+		if !v.resolvesBindingPath(v.qualify(typeName), binding.Path) {
+			v.report(SeverityError, DiagnosticUnresolved, position, target, "binding path '%s' doesn't lead to a navigation property", binding.Path)
+		}
+		if !v.resolvesBindingTarget(containerName, container, binding.Target) {
+			v.report(SeverityError, DiagnosticUnresolved, position, target, "binding target '%s' is not defined", binding.Target)
+		}
+	}
+}
+
+func (v *validator) checkContainer(containerName string, container *EntityContainer) {
+	v.checkExtends(containerName, container)
+
+	names := make(map[string]bool)
+	checkName := func(name string, position Position) {
+		target := fmt.Sprintf("%s/%s", containerName, name)
+		if !simpleIdentifier.MatchString(name) {
+			v.report(SeverityError, DiagnosticInvalidName, position, target, "'%s' is not a valid name", name)
+		}
+		if names[name] {
+			v.report(SeverityError, DiagnosticDuplicateName, position, target, "the name is used more than once in the container")
+		}
+		names[name] = true
+	}
+	checkEntitySet := func(entitySet string, position Position, target string) {
+		if entitySet != "" && !v.resolvesBindingTarget(containerName, container, entitySet) {
+			v.report(SeverityError, DiagnosticUnresolved, position, target, "entity set '%s' is not defined", entitySet)
+		}
+	}
+
+	for _, entitySet := range container.EntitySets {
+		checkName(entitySet.Name, entitySet.Position)
+		target := fmt.Sprintf("%s/%s", containerName, entitySet.Name)
+		v.checkAttributes(entitySet.UnknownAttributes, entitySet.Position, target, "Name", "EntityType")
+		if _, ok := v.entityTypes[v.qualify(entitySet.EntityType)]; !ok && !v.isReferenced(v.qualify(entitySet.EntityType)) {
+			v.report(SeverityError, DiagnosticUnresolved, entitySet.Position, target, "entity type '%s' is not defined", entitySet.EntityType)
+			continue
+		}
+		v.checkBindings(containerName, container, entitySet.Name, entitySet.EntityType, entitySet.NavigationPropertyBindings, entitySet.Position)
+	}
+
+	for _, singleton := range container.Singletons {
+		checkName(singleton.Name, singleton.Position)
+		target := fmt.Sprintf("%s/%s", containerName, singleton.Name)
+		if _, ok := v.entityTypes[v.qualify(singleton.Type)]; !ok && !v.isReferenced(v.qualify(singleton.Type)) {
+			v.report(SeverityError, DiagnosticUnresolved, singleton.Position, target, "entity type '%s' is not defined", singleton.Type)
+			continue
+		}
+		v.checkBindings(containerName, container, singleton.Name, singleton.Type, singleton.NavigationPropertyBindings, singleton.Position)
+	}
+
+	for _, functionImport := range container.FunctionImports {
+		checkName(functionImport.Name, functionImport.Position)
+		target := fmt.Sprintf("%s/%s", containerName, functionImport.Name)
+		name := v.qualify(functionImport.Function)
+		if _, ok := v.functions[name]; !ok && !v.isReferenced(name) {
+			v.report(SeverityError, DiagnosticUnresolved, functionImport.Position, target, "function '%s' is not defined", functionImport.Function)
+		}
+		checkEntitySet(functionImport.EntitySet, functionImport.Position, target)
+	}
+
+	for _, actionImport := range container.ActionImports {
+		checkName(actionImport.Name, actionImport.Position)
+		target := fmt.Sprintf("%s/%s", containerName, actionImport.Name)
+		name := v.qualify(actionImport.Action)
+		if _, ok := v.actions[name]; !ok && !v.isReferenced(name) {
+			v.report(SeverityError, DiagnosticUnresolved, actionImport.Position, target, "action '%s' is not defined", actionImport.Action)
+		}
+		checkEntitySet(actionImport.EntitySet, actionImport.Position, target)
+	}
+}
+
+// Collects the elements of the schemas by qualified name, reporting the names schemas use more than once
+func (v *validator) collect(edm *EdmxDocument) {
+	for _, reference := range edm.References {
+		for _, include := range reference.Includes {
+			v.referenced[include.Namespace] = true
+			if include.Alias != nil {
+				v.aliases[*include.Alias] = include.Namespace
+			}
+		}
+	}
+
+	schemas := edm.DataServices.Schemas
+	for i := range schemas {
+		if schemas[i].Alias != nil {
+			v.aliases[*schemas[i].Alias] = schemas[i].Namespace
+		}
+	}
+
+	for i := range schemas {
+		schema := &schemas[i]
+		for _, part := range strings.Split(schema.Namespace, ".") {
+			if !simpleIdentifier.MatchString(part) {
+				v.report(SeverityError, DiagnosticInvalidName, schema.Position, schema.Namespace, "'%s' is not a valid namespace", schema.Namespace)
+				break
+			}
+		}
+
+		names := make(map[string]bool)
+		declare := func(name string, position Position) string {
+			qualifiedName := fmt.Sprintf("%s.%s", schema.Namespace, name)
+			if !simpleIdentifier.MatchString(name) {
+				v.report(SeverityError, DiagnosticInvalidName, position, qualifiedName, "'%s' is not a valid name", name)
+			}
+			if names[name] {
+				v.report(SeverityError, DiagnosticDuplicateName, position, qualifiedName, "the name is used more than once in the schema")
+			}
+			names[name] = true
+			return qualifiedName
+		}
+
+		for j := range schema.EntityTypes {
+			v.entityTypes[declare(schema.EntityTypes[j].Name, schema.EntityTypes[j].Position)] = &schema.EntityTypes[j]
+		}
+		for j := range schema.ComplexTypes {
+			v.complexTypes[declare(schema.ComplexTypes[j].Name, schema.ComplexTypes[j].Position)] = &schema.ComplexTypes[j]
+		}
+		for j := range schema.EnumTypes {
+			qualifiedName := declare(schema.EnumTypes[j].Name, schema.EnumTypes[j].Position)
+			if _, ok := v.enumTypes[qualifiedName]; !ok {
+				v.enumTypes[qualifiedName] = &schema.EnumTypes[j]
+			}
+		}
+		for j := range schema.TypeDefinitions {
+			v.typeDefinitions[declare(schema.TypeDefinitions[j].Name, schema.TypeDefinitions[j].Position)] = &schema.TypeDefinitions[j]
+		}
+		for j := range schema.EntityContainers {
+			v.containers[declare(schema.EntityContainers[j].Name, schema.EntityContainers[j].Position)] = &schema.EntityContainers[j]
+		}
+
+		// Functions and actions may be overloaded, but a name is either the one of functions or the one of actions
+		operations := make(map[string]string)
+		declareOperation := func(name string, kind string, position Position) string {
+			qualifiedName := fmt.Sprintf("%s.%s", schema.Namespace, name)
+			switch operations[name] {
+			case "":
+				declare(name, position)
+				operations[name] = kind
+			case kind:
+			default:
+				v.report(SeverityError, DiagnosticDuplicateName, position, qualifiedName, "the name is used by both functions and actions")
+			}
+			return qualifiedName
+		}
+		for j := range schema.Functions {
+			qualifiedName := declareOperation(schema.Functions[j].Name, "function", schema.Functions[j].Position)
+			v.functions[qualifiedName] = append(v.functions[qualifiedName], &schema.Functions[j])
+		}
+		for j := range schema.Actions {
+			qualifiedName := declareOperation(schema.Actions[j].Name, "action", schema.Actions[j].Position)
+			v.actions[qualifiedName] = append(v.actions[qualifiedName], &schema.Actions[j])
+		}
+	}
+}
+
+// Checks the document against the structural rules of CSDL, e.g. that the types it refers to are defined
+// and that entity types have keys. Names from referenced documents are assumed to be valid.
+func Validate(edm *EdmxDocument) []Diagnostic {
+	v := &validator{
+		diagnostics:     []Diagnostic{},
+		aliases:         make(map[string]string),
+		referenced:      make(map[string]bool),
+		entityTypes:     make(map[string]*EntityType),
+		complexTypes:    make(map[string]*ComplexType),
+		enumTypes:       make(map[string]*EnumType),
+		typeDefinitions: make(map[string]*TypeDefinition),
+		functions:       make(map[string][]*Function),
+		actions:         make(map[string][]*Action),
+		containers:      make(map[string]*EntityContainer),
+	}
+	v.collect(edm)
+
+	for _, schema := range edm.DataServices.Schemas {
+		for i := range schema.EntityTypes {
+			v.checkEntityType(fmt.Sprintf("%s.%s", schema.Namespace, schema.EntityTypes[i].Name), &schema.EntityTypes[i])
+		}
+		for _, complexType := range schema.ComplexTypes {
+			qualifiedName := fmt.Sprintf("%s.%s", schema.Namespace, complexType.Name)
+			v.checkAttributes(complexType.UnknownAttributes, complexType.Position, qualifiedName, "Name", "BaseType", "Abstract", "OpenType")
+			v.checkBaseType(complexType.BaseType, false, qualifiedName, complexType.Position)
+			v.checkProperties(qualifiedName, complexType.Properties, complexType.NavigationProperties)
+		}
+		for i := range schema.EnumTypes {
+			v.checkEnumType(fmt.Sprintf("%s.%s", schema.Namespace, schema.EnumTypes[i].Name), &schema.EnumTypes[i])
+		}
+		for i := range schema.TypeDefinitions {
+			v.checkTypeDefinition(fmt.Sprintf("%s.%s", schema.Namespace, schema.TypeDefinitions[i].Name), &schema.TypeDefinitions[i])
+		}
+		for _, function := range schema.Functions {
+			qualifiedName := fmt.Sprintf("%s.%s", schema.Namespace, function.Name)
+			if function.ReturnType.Type == "" {
+				v.report(SeverityError, DiagnosticInvalidOperation, function.Position, qualifiedName, "the function has no return type")
+			}
+			v.checkOperation(qualifiedName, function.IsBound, function.EntitySetPath, function.Parameters, &function.ReturnType, function.Position)
+		}
+		for _, action := range schema.Actions {
+			qualifiedName := fmt.Sprintf("%s.%s", schema.Namespace, action.Name)
+			v.checkOperation(qualifiedName, action.IsBound, action.EntitySetPath, action.Parameters, action.ReturnType, action.Position)
+		}
+		for i := range schema.EntityContainers {
+			v.checkContainer(fmt.Sprintf("%s.%s", schema.Namespace, schema.EntityContainers[i].Name), &schema.EntityContainers[i])
+		}
+	}
+
+	return v.diagnostics
+}
@@ -0,0 +1,103 @@
+package odataschema
+
+import (
+	"strings"
+	"testing"
+)
+
+const aliasedNavigationMetadata = `<?xml version="1.0" encoding="utf-8"?>
+<edmx:Edmx Version="4.0" xmlns:edmx="http://docs.oasis-open.org/odata/ns/edmx">
+  <edmx:DataServices>
+    <Schema Namespace="Org.Example.Meta" Alias="Meta" xmlns="http://docs.oasis-open.org/odata/ns/edm">
+      <EntityType Name="Reference">
+        <Key>
+          <PropertyRef Name="Uri" />
+        </Key>
+        <Property Name="Uri" Type="Edm.String" Nullable="false" />
+        <NavigationProperty Name="Include" Type="Collection(Meta.Include)" />
+      </EntityType>
+      <EntityType Name="Include">
+        <Key>
+          <PropertyRef Name="Namespace" />
+        </Key>
+        <Property Name="Namespace" Type="Edm.String" Nullable="false" />
+        <NavigationProperty Name="Schema" Type="Meta.Schema" />
+      </EntityType>
+      <EntityType Name="Schema">
+        <Key>
+          <PropertyRef Name="Namespace" />
+        </Key>
+        <Property Name="Namespace" Type="Edm.String" Nullable="false" />
+      </EntityType>
+      <EntityContainer Name="Container">
+        <EntitySet Name="References" EntityType="Meta.Reference">
+          <NavigationPropertyBinding Path="%s" Target="Schemata" />
+        </EntitySet>
+        <EntitySet Name="Schemata" EntityType="Meta.Schema" />
+      </EntityContainer>
+    </Schema>
+  </edmx:DataServices>
+</edmx:Edmx>`
+
+func bindingDiagnostics(t *testing.T, path string) []Diagnostic {
+	t.Helper()
+	edm, err := ParseXML([]byte(strings.Replace(aliasedNavigationMetadata, "%s", path, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diagnostics := []Diagnostic{}
+	for _, diagnostic := range Validate(edm) {
+		if strings.Contains(diagnostic.String(), "binding path") {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	return diagnostics
+}
+
+func TestValidateBindingPathThroughAliasedCollection(t *testing.T) {
+	if diagnostics := bindingDiagnostics(t, "Include/Schema"); len(diagnostics) > 0 {
+		t.Errorf("expected the binding path to resolve, got %v", diagnostics)
+	}
+	if diagnostics := bindingDiagnostics(t, "Include/Missing"); len(diagnostics) != 1 {
+		t.Errorf("expected the binding path to be reported, got %v", diagnostics)
+	}
+}
+
+const unsupportedTypesMetadata = `<?xml version="1.0" encoding="utf-8"?>
+<edmx:Edmx Version="4.0" xmlns:edmx="http://docs.oasis-open.org/odata/ns/edmx">
+  <edmx:DataServices>
+    <Schema Namespace="Org.Example" xmlns="http://docs.oasis-open.org/odata/ns/edm">
+      <EntityType Name="Event">
+        <Key>
+          <PropertyRef Name="Id" />
+        </Key>
+        <Property Name="Id" Type="Edm.String" Nullable="false" />
+        <Property Name="StartsAt" Type="Edm.TimeOfDay" />
+        <Property Name="Area" Type="Collection(Edm.GeographyPolygon)" />
+        <NavigationProperty Name="Subject" Type="Edm.EntityType" />
+      </EntityType>
+      <TypeDefinition Name="Time" UnderlyingType="Edm.TimeOfDay" />
+      <Function Name="Describe">
+        <Parameter Name="value" Type="Edm.Untyped" />
+        <ReturnType Type="Edm.String" />
+      </Function>
+    </Schema>
+  </edmx:DataServices>
+</edmx:Edmx>`
+
+func TestValidateReportsUnsupportedTypes(t *testing.T) {
+	edm, err := ParseXML([]byte(unsupportedTypesMetadata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := []string{}
+	for _, diagnostic := range Validate(edm) {
+		if diagnostic.Code == DiagnosticUnsupportedType && diagnostic.Severity == SeverityWarning {
+			targets = append(targets, diagnostic.Target)
+		}
+	}
+	expected := "Org.Example.Event/StartsAt Org.Example.Event/Area Org.Example.Event/Subject Org.Example.Time Org.Example.Describe/value"
+	if strings.Join(targets, " ") != expected {
+		t.Errorf("expected the unsupported types of %s to be reported, got %v", expected, targets)
+	}
+}
@@ -0,0 +1,315 @@
+package odataschema
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+const (
+	edmxNamespace     = "http://docs.oasis-open.org/odata/ns/edmx"
+	edmNamespace      = "http://docs.oasis-open.org/odata/ns/edm"
+	metadataNamespace = "http://schemas.microsoft.com/ado/2007/08/dataservices/metadata"
+	coreVocabulary    = "Org.OData.Core.V1"
+)
+
+// Facets and attributes of CSDL v2/v3 which CSDL v4 dropped. The ones of the data services metadata namespace,
+// e.g. m:IsDefaultEntityContainer, are dropped as well.
+var legacyAttributes = map[string]bool{
+	"FixedLength":     true,
+	"Collation":       true,
+	"ConcurrencyMode": true,
+	"Mode":            true,
+}
+
+func edmxName(local string) xml.Name {
+	return xml.Name{Local: "edmx:" + local}
+}
+
+// Writes the document as CSDL XML. The edmx elements are written with the "edmx" prefix and the schemas
+// in the default namespace, as encoding/xml can't reproduce the prefixes of the original document.
+// CSDL v2/v3 documents are written as CSDL 4.0, since they've been normalized into their v4 counterparts when read.
+func WriteXML(w io.Writer, edm *EdmxDocument) error {
+	if edm.IsLegacy() {
+		edm = v4Document(edm)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	version := edm.Version
+	if version == "" {
+		version = "4.0"
+	}
+
+	root := xml.StartElement{
+		Name: edmxName("Edmx"),
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "xmlns:edmx"}, Value: edmxNamespace},
+			{Name: xml.Name{Local: "Version"}, Value: version},
+		},
+	}
+	root.Attr = append(root.Attr, edm.UnknownAttributes...)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	if err := encoder.EncodeToken(root); err != nil {
+		return err
+	}
+
+	for _, reference := range edm.References {
+		if err := writeReference(encoder, reference); err != nil {
+			return err
+		}
+	}
+
+	dataServices := xml.StartElement{Name: edmxName("DataServices"), Attr: edm.DataServices.UnknownAttributes}
+	if err := encoder.EncodeToken(dataServices); err != nil {
+		return err
+	}
+
+	for _, schema := range edm.DataServices.Schemas {
+		start := xml.StartElement{
+			Name: xml.Name{Local: "Schema"},
+			Attr: []xml.Attr{
+				{Name: xml.Name{Local: "xmlns"}, Value: edmNamespace},
+			},
+		}
+		if err := encoder.EncodeElement(schema, start); err != nil {
+			return err
+		}
+	}
+
+	if err := encoder.EncodeToken(dataServices.End()); err != nil {
+		return err
+	}
+	if err := encoder.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+// A copy of a normalized CSDL v2/v3 document as CSDL 4.0
+func v4Document(edm *EdmxDocument) *EdmxDocument {
+	document := *edm
+	document.Version = "4.0"
+	document.DataServices.DataServiceVersion = ""
+	document.DataServices.Schemas = make([]Schema, 0, len(edm.DataServices.Schemas))
+
+	documented := false
+	for _, schema := range edm.DataServices.Schemas {
+		schema, hasDocumentation := v4Schema(schema)
+		document.DataServices.Schemas = append(document.DataServices.Schemas, schema)
+		documented = documented || hasDocumentation
+	}
+
+	// Documentation is written as Core annotations, which the document then refers to
+	if documented && !includesNamespace(edm.References, coreVocabulary) {
+		document.References = append(append([]Reference{}, edm.References...), Reference{
+			Uri:      "https://oasis-tcs.github.io/odata-vocabularies/vocabularies/Org.OData.Core.V1.xml",
+			Includes: []Include{{Namespace: coreVocabulary}},
+		})
+	}
+	return &document
+}
+
+func includesNamespace(references []Reference, namespace string) bool {
+	for _, reference := range references {
+		for _, include := range reference.Includes {
+			if include.Namespace == namespace {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Rewrites the elements of a normalized CSDL v2/v3 schema the way CSDL v4 declares them. Associations, their sets and
+// the roles of navigation properties are dropped, as the navigation properties and their bindings carry them already.
+// Documentation becomes Core annotations. Also tells whether there was any documentation.
+func v4Schema(schema Schema) (Schema, bool) {
+	w := &v4Writer{}
+	schema.Associations = nil
+	schema.Annotations = w.annotations(schema.Annotations, &schema.Documentation)
+	schema.UnknownAttributes = v4Attributes(schema.UnknownAttributes)
+
+	entityTypes := make([]EntityType, 0, len(schema.EntityTypes))
+	for _, entityType := range schema.EntityTypes {
+		entityType.Properties = w.properties(entityType.Properties)
+		entityType.NavigationProperties = w.navigationProperties(entityType.NavigationProperties)
+		entityType.Annotations = w.annotations(entityType.Annotations, &entityType.Documentation)
+		entityType.UnknownAttributes = v4Attributes(entityType.UnknownAttributes)
+		entityTypes = append(entityTypes, entityType)
+	}
+	schema.EntityTypes = entityTypes
+
+	complexTypes := make([]ComplexType, 0, len(schema.ComplexTypes))
+	for _, complexType := range schema.ComplexTypes {
+		complexType.Properties = w.properties(complexType.Properties)
+		complexType.NavigationProperties = w.navigationProperties(complexType.NavigationProperties)
+		complexType.Annotations = w.annotations(complexType.Annotations, &complexType.Documentation)
+		complexType.UnknownAttributes = v4Attributes(complexType.UnknownAttributes)
+		complexTypes = append(complexTypes, complexType)
+	}
+	schema.ComplexTypes = complexTypes
+
+	enumTypes := make([]EnumType, 0, len(schema.EnumTypes))
+	for _, enumType := range schema.EnumTypes {
+		members := make([]EnumTypeMember, 0, len(enumType.Members))
+		for _, member := range enumType.Members {
+			member.Annotations = w.annotations(member.Annotations, &member.Documentation)
+			member.UnknownAttributes = v4Attributes(member.UnknownAttributes)
+			members = append(members, member)
+		}
+		enumType.Members = members
+		enumType.Annotations = w.annotations(enumType.Annotations, &enumType.Documentation)
+		enumType.UnknownAttributes = v4Attributes(enumType.UnknownAttributes)
+		enumTypes = append(enumTypes, enumType)
+	}
+	schema.EnumTypes = enumTypes
+
+	functions := make([]Function, 0, len(schema.Functions))
+	for _, function := range schema.Functions {
+		function.Parameters = w.parameters(function.Parameters)
+		function.Annotations = w.annotations(function.Annotations, &function.Documentation)
+		function.UnknownAttributes = v4Attributes(function.UnknownAttributes)
+		functions = append(functions, function)
+	}
+	schema.Functions = functions
+
+	actions := make([]Action, 0, len(schema.Actions))
+	for _, action := range schema.Actions {
+		action.Parameters = w.parameters(action.Parameters)
+		action.Annotations = w.annotations(action.Annotations, &action.Documentation)
+		action.UnknownAttributes = v4Attributes(action.UnknownAttributes)
+		actions = append(actions, action)
+	}
+	schema.Actions = actions
+
+	containers := make([]EntityContainer, 0, len(schema.EntityContainers))
+	for _, container := range schema.EntityContainers {
+		containers = append(containers, w.container(container))
+	}
+	schema.EntityContainers = containers
+
+	return schema, w.documented
+}
+
+type v4Writer struct {
+	documented bool
+}
+
+// Adds the summary and the long description of the documentation as Core annotations, clearing the documentation
+func (w *v4Writer) annotations(annotations []Annotation, documentation **Documentation) []Annotation {
+	if *documentation == nil {
+		return annotations
+	}
+	result := append([]Annotation{}, annotations...)
+	if summary := (*documentation).Summary; summary != nil {
+		result = append(result, Annotation{Term: coreVocabulary + ".Description", Expression: Expression{String: summary}})
+	}
+	if longDescription := (*documentation).LongDescription; longDescription != nil {
+		result = append(result, Annotation{Term: coreVocabulary + ".LongDescription", Expression: Expression{String: longDescription}})
+	}
+	*documentation = nil
+	w.documented = true
+	return result
+}
+
+func v4Attributes(attributes UnknownAttributes) UnknownAttributes {
+	var result UnknownAttributes
+	for _, attr := range attributes {
+		if attr.Name.Space == metadataNamespace || (attr.Name.Space == "" && legacyAttributes[attr.Name.Local]) {
+			continue
+		}
+		result = append(result, attr)
+	}
+	return result
+}
+
+// Collections are typed as "Collection(...)" already, so the TypeRef of CSDL v3 collections is dropped
+func (w *v4Writer) properties(properties []Property) []Property {
+	result := make([]Property, 0, len(properties))
+	for _, property := range properties {
+		property.TypeRef = nil
+		property.Annotations = w.annotations(property.Annotations, &property.Documentation)
+		property.UnknownAttributes = v4Attributes(property.UnknownAttributes)
+		result = append(result, property)
+	}
+	return result
+}
+
+func (w *v4Writer) navigationProperties(navProps []NavigationProperty) []NavigationProperty {
+	result := make([]NavigationProperty, 0, len(navProps))
+	for _, navProp := range navProps {
+		navProp.Relationship = nil
+		navProp.FromRole = ""
+		navProp.ToRole = ""
+		navProp.Annotations = w.annotations(navProp.Annotations, &navProp.Documentation)
+		navProp.UnknownAttributes = v4Attributes(navProp.UnknownAttributes)
+		result = append(result, navProp)
+	}
+	return result
+}
+
+func (w *v4Writer) parameters(parameters []Parameter) []Parameter {
+	result := make([]Parameter, 0, len(parameters))
+	for _, parameter := range parameters {
+		parameter.Annotations = w.annotations(parameter.Annotations, &parameter.Documentation)
+		parameter.UnknownAttributes = v4Attributes(parameter.UnknownAttributes)
+		result = append(result, parameter)
+	}
+	return result
+}
+
+// Function imports keep only the function they import, as the operation is declared in the schema
+func (w *v4Writer) container(container EntityContainer) EntityContainer {
+	container.AssociationSets = nil
+	container.Annotations = w.annotations(container.Annotations, &container.Documentation)
+	container.UnknownAttributes = v4Attributes(container.UnknownAttributes)
+
+	entitySets := make([]EntitySet, 0, len(container.EntitySets))
+	for _, entitySet := range container.EntitySets {
+		entitySet.Annotations = w.annotations(entitySet.Annotations, &entitySet.Documentation)
+		entitySet.UnknownAttributes = v4Attributes(entitySet.UnknownAttributes)
+		entitySets = append(entitySets, entitySet)
+	}
+	container.EntitySets = entitySets
+
+	functionImports := make([]FunctionImport, 0, len(container.FunctionImports))
+	for _, functionImport := range container.FunctionImports {
+		functionImport.ReturnType = nil
+		functionImport.HttpMethod = nil
+		functionImport.IsBindable = false
+		functionImport.IsSideEffecting = nil
+		functionImport.Parameters = nil
+		functionImport.Annotations = w.annotations(functionImport.Annotations, &functionImport.Documentation)
+		functionImport.UnknownAttributes = v4Attributes(functionImport.UnknownAttributes)
+		functionImports = append(functionImports, functionImport)
+	}
+	container.FunctionImports = functionImports
+
+	return container
+}
+
+func writeReference(encoder *xml.Encoder, reference Reference) error {
+	start := xml.StartElement{
+		Name: edmxName("Reference"),
+		Attr: []xml.Attr{{Name: xml.Name{Local: "Uri"}, Value: reference.Uri}},
+	}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for _, include := range reference.Includes {
+		if err := encoder.EncodeElement(include, xml.StartElement{Name: edmxName("Include")}); err != nil {
+			return err
+		}
+	}
+	for _, includeAnnotations := range reference.IncludeAnnotations {
+		if err := encoder.EncodeElement(includeAnnotations, xml.StartElement{Name: edmxName("IncludeAnnotations")}); err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
@@ -0,0 +1,73 @@
+package odataschema
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Namespaces and types of CSDL v2/v3, which written documents must not contain
+var legacyConstructs = []string{
+	"http://schemas.microsoft.com/ado/",
+	`"Edm.DateTime"`,
+	"<Association ",
+	"<AssociationSet ",
+	"Relationship=",
+}
+
+func TestWriteXMLRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("../schemas/*.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no schemas to write")
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			edm, err := Parse(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := edm
+			if edm.IsLegacy() {
+				expected = v4Document(edm)
+			}
+
+			var written bytes.Buffer
+			if err := WriteXML(&written, edm); err != nil {
+				t.Fatal(err)
+			}
+			for _, construct := range legacyConstructs {
+				if strings.Contains(written.String(), construct) {
+					t.Errorf("the written document contains '%s'", construct)
+				}
+			}
+
+			read, err := ParseXML(written.Bytes())
+			if err != nil {
+				t.Fatalf("the written document can't be read: %v", err)
+			}
+			if read.IsLegacy() {
+				t.Errorf("the written document is of OData version %s", read.ODataVersion())
+			}
+
+			var rewritten bytes.Buffer
+			if err := WriteXML(&rewritten, read); err != nil {
+				t.Fatal(err)
+			}
+			if written.String() != rewritten.String() {
+				t.Errorf("writing the document again changes it")
+			}
+
+			normalizeModel(reflect.ValueOf(expected))
+			normalizeModel(reflect.ValueOf(read))
+			if !reflect.DeepEqual(expected, read) {
+				t.Errorf("the written document reads into a different model")
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+var irregularPlurals = map[string]string{
+	"person":    "people",
+	"man":       "men",
+	"woman":     "women",
+	"child":     "children",
+	"tooth":     "teeth",
+	"foot":      "feet",
+	"mouse":     "mice",
+	"goose":     "geese",
+	"ox":        "oxen",
+	"datum":     "data",
+	"medium":    "media",
+	"criterion": "criteria",
+	"index":     "indices",
+	"matrix":    "matrices",
+	"vertex":    "vertices",
+	"quiz":      "quizzes",
+	"leaf":      "leaves",
+	"life":      "lives",
+	"knife":     "knives",
+	"wife":      "wives",
+	"half":      "halves",
+	"shelf":     "shelves",
+	"hero":      "heroes",
+	"potato":    "potatoes",
+	"tomato":    "tomatoes",
+	"echo":      "echoes",
+}
+
+var uncountables = []string{
+	"equipment", "information", "rice", "money", "species", "series", "fish", "sheep", "deer",
+	"news", "metadata", "feedback", "software", "hardware", "content", "staff", "analytics",
+}
+
+// Singular words ending in "s", which the rules would otherwise take for plurals
+var singularsEndingInS = []string{"alias", "atlas", "bias", "canvas", "gas", "lens"}
+
+// Singular words ending in "ie", whose plurals the rules would otherwise singularize to "y", e.g. "movies" to "movy"
+var singularsEndingInIe = []string{"movie", "cookie", "zombie", "rookie", "calorie", "selfie", "hoodie", "genie"}
+
+// Pluralizes and singularizes English words. Only the last word of a camel or Pascal case name is inflected.
+type Inflector struct {
+	plurals   map[string]string
+	singulars map[string]string
+}
+
+// The overrides map singular words or whole names to their plurals and take precedence over the rules
+func NewInflector(overrides map[string]string) *Inflector {
+	inflector := &Inflector{
+		plurals:   make(map[string]string),
+		singulars: make(map[string]string),
+	}
+
+	for singular, plural := range irregularPlurals {
+		inflector.plurals[singular] = plural
+		inflector.singulars[plural] = singular
+	}
+
+	for _, singular := range singularsEndingInS {
+		inflector.plurals[singular] = singular + "es"
+		inflector.singulars[singular+"es"] = singular
+	}
+
+	for _, word := range uncountables {
+		inflector.plurals[word] = word
+		inflector.singulars[word] = word
+	}
+
+	for singular, plural := range overrides {
+		inflector.plurals[strings.ToLower(singular)] = strings.ToLower(plural)
+		inflector.singulars[strings.ToLower(plural)] = strings.ToLower(singular)
+	}
+
+	// Inflecting a word that already has the wanted number keeps it, e.g. the plural of "People" is "People"
+	for singular := range inflector.plurals {
+		if _, found := inflector.singulars[singular]; !found {
+			inflector.singulars[singular] = singular
+		}
+	}
+	for plural := range inflector.singulars {
+		if _, found := inflector.plurals[plural]; !found {
+			inflector.plurals[plural] = plural
+		}
+	}
+
+	return inflector
+}
+
+func isVowel(r byte) bool {
+	return strings.IndexByte("aeiou", r) >= 0
+}
+
+// Splits "NewsItem" into "News" and "Item", and "news_item" into "news_" and "item"
+func splitLastWord(name string) (string, string) {
+	runes := []rune(name)
+	for i := len(runes) - 1; i > 0; i-- {
+		if runes[i-1] == '_' || (unicode.IsUpper(runes[i]) && !unicode.IsUpper(runes[i-1])) {
+			return string(runes[:i]), string(runes[i:])
+		}
+	}
+	return "", name
+}
+
+// Applies the casing of the original word to the inflected one. Acronyms keep their upper case letters,
+// so "URL" becomes "URLs" and "URLs" becomes "URL".
+func matchCase(original string, inflected string) string {
+	upperCount := 0
+	for _, r := range original {
+		if !unicode.IsUpper(r) {
+			break
+		}
+		upperCount += 1
+	}
+
+	runes := []rune(inflected)
+	for i := 0; i < upperCount && i < len(runes); i++ {
+		runes[i] = unicode.ToUpper(runes[i])
+	}
+	return string(runes)
+}
+
+func (inflector *Inflector) inflect(name string, overrides map[string]string, inflectWord func(string) string) string {
+	if name == "" {
+		return name
+	}
+
+	if inflected, found := overrides[strings.ToLower(name)]; found {
+		return matchCase(name, inflected)
+	}
+
+	prefix, word := splitLastWord(name)
+	lowerWord := strings.ToLower(word)
+
+	if inflected, found := overrides[lowerWord]; found {
+		return prefix + matchCase(word, inflected)
+	}
+
+	return prefix + matchCase(word, inflectWord(lowerWord))
+}
+
+func (inflector *Inflector) Pluralize(name string) string {
+	return inflector.inflect(name, inflector.plurals, pluralizeWord)
+}
+
+func (inflector *Inflector) Singularize(name string) string {
+	return inflector.inflect(name, inflector.singulars, singularizeWord)
+}
+
+func pluralizeWord(word string) string {
+	switch {
+	case strings.HasSuffix(word, "is"):
+		return word[:len(word)-2] + "es"
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"),
+		strings.HasSuffix(word, "x"), strings.HasSuffix(word, "z"),
+		strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	case strings.HasSuffix(word, "s"):
+		// Most likely plural already, e.g. "Settings"
+		return word
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !isVowel(word[len(word)-2]):
+		return word[:len(word)-1] + "ies"
+	default:
+		return word + "s"
+	}
+}
+
+func singularizeWord(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && SliceContainsString(singularsEndingInIe, word[:len(word)-1]):
+		return word[:len(word)-1]
+	case strings.HasSuffix(word, "ies") && len(word) > 3:
+		return word[:len(word)-3] + "y"
+	// "houses" and "causes" unlike "statuses", "caches" and "headaches" unlike "beaches" and "branches"
+	case strings.HasSuffix(word, "ouses"), strings.HasSuffix(word, "auses"), word == "uses",
+		strings.HasSuffix(word, "aches") && len(word) > 5 && !isVowel(word[len(word)-6]):
+		return word[:len(word)-1]
+	case strings.HasSuffix(word, "yses"):
+		return word[:len(word)-2] + "is"
+	case strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "uses"),
+		strings.HasSuffix(word, "xes"), strings.HasSuffix(word, "zes"),
+		strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"), strings.HasSuffix(word, "is"):
+		return word
+	case strings.HasSuffix(word, "s"):
+		return word[:len(word)-1]
+	default:
+		return word
+	}
+}
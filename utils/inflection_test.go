@@ -0,0 +1,47 @@
+package utils
+
+import "testing"
+
+func TestInflector(t *testing.T) {
+	inflector := NewInflector(map[string]string{"Cactus": "Cacti"})
+	for _, test := range []struct {
+		singular string
+		plural   string
+	}{
+		{"Person", "People"},
+		{"Category", "Categories"},
+		{"Day", "Days"},
+		{"Address", "Addresses"},
+		{"Status", "Statuses"},
+		{"OrderStatus", "OrderStatuses"},
+		{"Alias", "Aliases"},
+		{"Box", "Boxes"},
+		{"Branch", "Branches"},
+		{"Analysis", "Analyses"},
+		{"House", "Houses"},
+		{"Cause", "Causes"},
+		{"Course", "Courses"},
+		{"Cache", "Caches"},
+		{"Beach", "Beaches"},
+		{"Movie", "Movies"},
+		{"NewsItem", "NewsItems"},
+		{"News", "News"},
+		{"ContentURL", "ContentURLs"},
+		{"Cactus", "Cacti"},
+	} {
+		t.Run(test.singular, func(t *testing.T) {
+			if plural := inflector.Pluralize(test.singular); plural != test.plural {
+				t.Errorf("expected the plural of '%s' to be '%s', got '%s'", test.singular, test.plural, plural)
+			}
+			if plural := inflector.Pluralize(test.plural); plural != test.plural {
+				t.Errorf("expected the plural of '%s' to be '%s', got '%s'", test.plural, test.plural, plural)
+			}
+			if singular := inflector.Singularize(test.plural); singular != test.singular {
+				t.Errorf("expected the singular of '%s' to be '%s', got '%s'", test.plural, test.singular, singular)
+			}
+			if singular := inflector.Singularize(test.singular); singular != test.singular {
+				t.Errorf("expected the singular of '%s' to be '%s', got '%s'", test.singular, test.singular, singular)
+			}
+		})
+	}
+}